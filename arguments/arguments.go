@@ -1,6 +1,7 @@
 package arguments
 
 type Arguments struct {
+	Command     string
 	Loops       int
 	Iterations  int
 	Mode        string
@@ -15,4 +16,41 @@ type Arguments struct {
 	NoDataCheck bool
 	Spatterns   bool
 	Sessovrd    string
+	Output      string
+	OutFile     string
+
+	MaxErrorRate float64
+	MaxP99       string
+	MinTPS       float64
+
+	Cleanup           bool
+	DropSession       bool
+	PurgeAge          string
+	PurgeUnregistered bool
+	ListOnly          bool
+
+	Seed int64
+
+	Workload   string
+	Phase      string
+	Properties map[string]string
+	Mix        string
+
+	Distribution string
+	ZipfTheta    float64
+	HotFraction  float64
+	HotOps       float64
+
+	Rate    float64
+	Profile string
+
+	Search       string
+	SearchMode   string
+	SearchSteps  int
+	SLOP99       string
+	SLOErrorRate float64
+
+	ReportInterval string
+	ReportFile     string
+	Warmup         string
 }
@@ -1,50 +1,91 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/hartsp2000/benchmark_db/arguments"
 	"github.com/hartsp2000/benchmark_db/config"
+	"github.com/hartsp2000/benchmark_db/dataset"
 	"github.com/hartsp2000/benchmark_db/db"
-	"github.com/hartsp2000/benchmark_db/memory"
+	"github.com/hartsp2000/benchmark_db/distribution"
+	"github.com/hartsp2000/benchmark_db/report"
+	"github.com/hartsp2000/benchmark_db/session"
 	"github.com/hartsp2000/benchmark_db/statistics"
+	"github.com/hartsp2000/benchmark_db/timeparse"
 	"github.com/hartsp2000/benchmark_db/version"
+	"github.com/hartsp2000/benchmark_db/workload"
 	"math/rand"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
 var configfile = "benchmark_db.conf"
 
-// WARNING - In junkBytes Don't use ":" for random data generation.  ":" is reserved for redis functions
+// PROCESS EXIT CODES
+const (
+	ExitSuccess   = 0 // the test ran and met every threshold
+	ExitUsage     = 1 // invalid command line or unknown database type
+	ExitSchema    = 2 // the test tables could not be created or dropped
+	ExitConnect   = 3 // the connection to the database failed
+	ExitVerify    = 4 // data read back did not match what was written
+	ExitThreshold = 5 // a -max-error-rate, -max-p99 or -min-tps threshold was breached
+
+	ExitInterrupted = 130 // stopped by SIGINT/SIGTERM, partial results were reported
+)
 
-const junkBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()=+-;~/?<>[]{}_"
 const sessBytes = "ABCDEFGHIJKLMNOPQRSTUVWXYZ123456789"
 
-var JunkData [][]string
-var JunkKey [][]string
+var SessionName string
 
-var AvailData map[int]memory.Memory
+// Commands are the optional subcommands given before the flags.
+var Commands = []string{"load", "run", "cleanup", "list"}
 
-var SessionName string
+// propertyFlags collects the repeated -prop name=value workload properties.
+type propertyFlags map[string]string
 
-var WriteErrors, ReadErrors int
-var IterRead, IterWrite [131073]time.Duration
+func (properties propertyFlags) String() string {
+	var parts []string
+	for name, value := range properties {
+		parts = append(parts, name+"="+value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (properties propertyFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+	properties[strings.ToLower(parts[0])] = parts[1]
+	return nil
+}
 
 func DisplayHelp() {
 	fmt.Printf("Benchmark DB Tool - Version %s (%s)\n", version.VERSION, version.BUILDID)
+	fmt.Printf("\nUsage: benchmark_db [command] [flags]\n\n")
+	fmt.Printf("    load       create a session and write its records once (or the load phase of a -workload)\n")
+	fmt.Printf("    run        benchmark the records of a loaded -session, without writing them first\n")
+	fmt.Printf("    cleanup    drop the -session, or the sessions older than -purge\n")
+	fmt.Printf("    list       list the registered sessions\n")
+	fmt.Printf("\nWithout a command the session is created, written and benchmarked in one run.\n\n")
 	flag.PrintDefaults()
-	fmt.Printf("\n")
-	os.Exit(1)
+	fmt.Printf("\nExit codes: %d=success, %d=usage, %d=schema failure, %d=connect failure, "+
+		"%d=data verification failure, %d=threshold breach, %d=interrupted\n\n", ExitSuccess, ExitUsage, ExitSchema,
+		ExitConnect, ExitVerify, ExitThreshold, ExitInterrupted)
+	os.Exit(ExitUsage)
 }
 
 func CommandLineArgs() arguments.Arguments {
 	var help = flag.Bool("help", false, "Display list of commands")
 	var nodatacheck = flag.Bool("ndc", false, "Skip data checking")
 	var parallel = flag.Bool("p", false, "run in parallel mode (sequential test only!)")
-	var loops = flag.Int("loops", 1, "Number of test loops (max 131072)")
-	var iterations = flag.Int("iter", 1000, "Number of times to repeat the reads/writes (max 131072)")
+	var loops = flag.Int("loops", 1, "Number of test loops")
+	var iterations = flag.Int("iter", 1000, "Number of times to repeat the reads/writes")
 	var mode = flag.String("mode", "rw", "r=read(for tps); w=write; rw=read/write")
 	var dataBS = flag.Int("dbs", 1024, "Data Block byte size (for data writes)")
 	var keyBS = flag.Int("kbs", 20, "Key Block byte size (for key size)")
@@ -56,9 +97,74 @@ func CommandLineArgs() arguments.Arguments {
 	var tpsWorkers = flag.Int("tw", 1, "TPS Test: Number of workers (threads) Must be 1 or equal "+
 		"to the number of loops!!")
 	var spatterns = flag.Bool("stored", false, "Use stored patterns from db in the config file")
-	var sessovrd = flag.String("session", "", "Use an existing session name. (Don't create tables)")
-
-	flag.Parse()
+	var sessovrd = flag.String("session", "", "Use an existing session name. (Don't create tables, the session's "+
+		"-loops, -iter, -kbs and -dbs are loaded from the registry)")
+	var output = flag.String("output", "text", "Results format: text, json or csv")
+	var outFile = flag.String("out-file", "", "File to write json/csv results to. (default: benchmark_db_<session>.<format>, - for stdout)")
+	var maxErrorRate = flag.Float64("max-error-rate", 0, "Fail the run if more than this fraction (0-1) of the operations fail. (0: any "+
+		"error fails the run, -1 disables, -search disables it unless given)")
+	var maxP99 = flag.String("max-p99", "", "Fail the run if the read or write p99 latency is above this value. (eg: 500us, 50ms, 1s)")
+	var minTPS = flag.Float64("min-tps", 0, "Fail the run if the throughput is below this many operations per second. (0 disables)")
+	var cleanup = flag.Bool("cleanup", false, "Drop the session's tables/keys after the run (also when interrupted)")
+	var dropSession = flag.Bool("drop", false, "Only drop the tables/keys of the -session and exit. (-loops is used "+
+		"as the table count for sessions missing from the registry)")
+	var purgeAge = flag.String("purge", "", "Only list the registered sessions, drop the ones older than this age and exit. (eg: 12h, 168h)")
+	var purgeUnregistered = flag.Bool("purge-unregistered", false, "With -purge, also drop the test tables/keys of the "+
+		"sessions missing from the registry, whatever their age")
+	var listSessions = flag.Bool("list-sessions", false, "Only list the registered sessions and exit")
+	var coreWorkload = flag.String("workload", "", "Run a YCSB core workload (a to f) on the first table of the session "+
+		"instead of -mode. -tw sets the threads, -dur (optional) limits the run phase")
+	var phase = flag.String("phase", "both", "YCSB phase: load, run (needs -session) or both")
+	properties := make(propertyFlags)
+	flag.Var(properties, "prop", "YCSB workload property name=value, can be repeated. (recordcount, operationcount, "+
+		"readproportion, updateproportion, insertproportion, scanproportion, readmodifywriteproportion, "+
+		"requestdistribution, maxscanlength)")
+	var mix = flag.String("mix", "", "TPS rw test: proportions of read, insert, update, delete, rmw and scan "+
+		"operations. (eg: read=0.95,update=0.05, default: "+workload.DefaultMix+")")
+	var dist = flag.String("dist", "uniform", "TPS r and rw tests: request distribution of the records, one of "+
+		strings.Join(distribution.Names, ", "))
+	var zipfTheta = flag.Float64("zipf-theta", distribution.DefaultOptions.Theta, "Skew of the zipfian, scrambledzipfian "+
+		"and latest distributions (0-1)")
+	var hotFraction = flag.Float64("hot-fraction", distribution.DefaultOptions.HotFraction, "hotspot distribution: "+
+		"fraction of the records that are hot")
+	var hotOps = flag.Float64("hot-ops", distribution.DefaultOptions.HotOps, "hotspot distribution: fraction of the "+
+		"operations that go to the hot records")
+	var rate = flag.Float64("rate", 0, "TPS and YCSB tests: open loop target of operations per second across all "+
+		"workers, latencies are also reported from the intended start times. (0 disables)")
+	var profile = flag.String("profile", "", "TPS and YCSB run tests: load profile, one result per stage. "+
+		"ramp:FROM-TO@DURATION[/STAGES] (ops/sec), steps:RATE@HOLD,... or workers:N@HOLD,... "+
+		"(eg: ramp:1000-10000@5m, steps:1000@1m,2000@1m, workers:4@30s,8@30s)")
+	var search = flag.String("search", "", "TPS and YCSB run tests: search the highest rate between MIN and MAX "+
+		"ops/sec that meets -slo-p99 and -slo-error-rate, running each rate for HOLD. (eg: 1000-50000@1m)")
+	var searchMode = flag.String("search-mode", "binary", "How -search picks the rates: "+strings.Join(workload.SearchModes, ", "))
+	var searchSteps = flag.Int("search-steps", 8, "Number of rates -search tries (after MIN and MAX for a binary search)")
+	var sloP99 = flag.String("slo-p99", "", "-search: highest p99 latency, from the intended start times (eg: 10ms)")
+	var sloErrorRate = flag.Float64("slo-error-rate", 0.01, "-search: highest fraction (0-1) of failed operations (-1 disables)")
+	var reportInterval = flag.String("report-interval", "", "TPS and YCSB tests: print the throughput, errors and "+
+		"latencies of every interval while the test runs and write them to -report-file. (eg: 10s, 1m)")
+	var reportFile = flag.String("report-file", "", "Time series csv file of -report-interval. "+
+		"(default: benchmark_db_<session>_intervals.csv)")
+	var warmup = flag.String("warmup", "", "TPS and YCSB tests: run this long (eg: 30s) or this many operations (eg: 10000) "+
+		"at the start of every test before measuring, the warm-up results are reported apart")
+	var seed = flag.Int64("seed", 0, "Random seed for the session name and the operation sequences of the workers. "+
+		"(0 picks one, the seed used is printed and saved with the results)")
+
+	// THE OPTIONAL COMMAND COMES BEFORE THE FLAGS
+	command := ""
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		for _, known := range Commands {
+			if args[0] == known {
+				command = known
+			}
+		}
+		if len(command) == 0 {
+			fmt.Printf("Fatal: Unknown command: %s\n\n", args[0])
+			DisplayHelp()
+		}
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	if *help {
 		DisplayHelp()
@@ -68,18 +174,182 @@ func CommandLineArgs() arguments.Arguments {
 		DisplayHelp()
 	}
 
-	if (*tpsWorkers != 1) && (*tpsWorkers != *loops) {
+	if !(*output == "text" || *output == "json" || *output == "csv") {
+		DisplayHelp()
+	}
+
+	switch command {
+	case "load":
+		if len(*sessovrd) > 0 || *tps || *cleanup {
+			fmt.Printf("Fatal: load creates a new session and writes its records, without -session, -tps or -cleanup.\n\n")
+			DisplayHelp()
+		}
+		if len(*coreWorkload) > 0 {
+			*phase = "load"
+		}
+	case "run":
+		if len(*sessovrd) == 0 {
+			fmt.Printf("Fatal: run needs the -session of a loaded session.\n\n")
+			DisplayHelp()
+		}
+		if len(*coreWorkload) > 0 {
+			*phase = "run"
+		}
+	case "cleanup":
+		if len(*sessovrd) == 0 && len(*purgeAge) == 0 {
+			fmt.Printf("Fatal: cleanup needs the -session to drop or a -purge age.\n\n")
+			DisplayHelp()
+		}
+		*dropSession = len(*sessovrd) > 0
+	case "list":
+		*listSessions = true
+	}
+
+	if len(*mix) > 0 {
+		if !*tps || *mode != "rw" {
+			fmt.Printf("Fatal: -mix needs -tps -mode rw.\n\n")
+			DisplayHelp()
+		}
+		parsed, err := workload.ParseMix(*mix)
+		if err != nil {
+			fmt.Printf("Fatal: %s\n\n", err)
+			DisplayHelp()
+		}
+		// THE OTHER OPERATIONS ONLY PICK RECORDS THAT WERE WRITTEN
+		if !parsed.Has(workload.OpInsert) && len(*sessovrd) == 0 && !*spatterns {
+			fmt.Printf("Fatal: -mix without insert needs the records of a loaded -session or the -stored patterns.\n\n")
+			DisplayHelp()
+		}
+	}
+
+	if *rate > 0 {
+		if !*tps && len(*coreWorkload) == 0 && command != "load" {
+			fmt.Printf("Fatal: -rate needs -tps, -workload or the load command.\n\n")
+			DisplayHelp()
+		}
+		if timeparse.ParseDuration(*interval) > 0 {
+			fmt.Printf("Fatal: -rate and -delay can't be used together.\n\n")
+			DisplayHelp()
+		}
+	}
+
+	if len(*profile) > 0 {
+		stages, err := workload.ParseProfile(*profile)
+		if err != nil {
+			fmt.Printf("Fatal: %s\n\n", err)
+			DisplayHelp()
+		}
+		if !*tps && (len(*coreWorkload) == 0 || *phase == "load") {
+			fmt.Printf("Fatal: -profile needs -tps or the run phase of a -workload.\n\n")
+			DisplayHelp()
+		}
+		if stages[0].Rate > 0 && (*rate > 0 || timeparse.ParseDuration(*interval) > 0) {
+			fmt.Printf("Fatal: a ramp or steps -profile sets the rate, it can't be used with -rate or -delay.\n\n")
+			DisplayHelp()
+		}
+	}
+
+	if len(*search) > 0 {
+		slo := workload.SLO{P99: timeparse.ParseDuration(*sloP99), ErrorRate: *sloErrorRate}
+		if _, err := workload.NewSearch(*search, *searchMode, *searchSteps, slo); err != nil {
+			fmt.Printf("Fatal: %s\n\n", err)
+			DisplayHelp()
+		}
+		if !*tps && (len(*coreWorkload) == 0 || *phase == "load") {
+			fmt.Printf("Fatal: -search needs -tps or the run phase of a -workload.\n\n")
+			DisplayHelp()
+		}
+		if len(*profile) > 0 || *rate > 0 || timeparse.ParseDuration(*interval) > 0 {
+			fmt.Printf("Fatal: -search sets the rate, it can't be used with -profile, -rate or -delay.\n\n")
+			DisplayHelp()
+		}
+		if slo.P99 <= 0 && slo.ErrorRate < 0 {
+			fmt.Printf("Fatal: -search needs a -slo-p99 or -slo-error-rate.\n\n")
+			DisplayHelp()
+		}
+	}
+
+	if len(*reportInterval) > 0 {
+		if interval, err := time.ParseDuration(*reportInterval); err != nil || interval <= 0 {
+			fmt.Printf("Fatal: Invalid -report-interval: %s\n\n", *reportInterval)
+			DisplayHelp()
+		}
+		if !*tps && len(*coreWorkload) == 0 && command != "load" {
+			fmt.Printf("Fatal: -report-interval needs -tps, -workload or the load command.\n\n")
+			DisplayHelp()
+		}
+	}
+
+	if len(*warmup) > 0 {
+		if _, _, err := workload.ParseWarmup(*warmup); err != nil {
+			fmt.Printf("Fatal: %s\n\n", err)
+			DisplayHelp()
+		}
+		if !*tps && len(*coreWorkload) == 0 {
+			fmt.Printf("Fatal: -warmup needs -tps or -workload.\n\n")
+			DisplayHelp()
+		}
+	}
+
+	options := distribution.Options{Theta: *zipfTheta, HotFraction: *hotFraction, HotOps: *hotOps}
+	if _, err := distribution.New(*dist, options); err != nil {
+		fmt.Printf("Fatal: %s\n\n", err)
+		DisplayHelp()
+	}
 
+	if len(*coreWorkload) > 0 {
+		core, err := workload.NewCoreWorkload(*coreWorkload, properties, int64(*iterations), options)
+		if err != nil {
+			fmt.Printf("Fatal: %s\n\n", err)
+			DisplayHelp()
+		}
+		if *spatterns {
+			fmt.Printf("Fatal: -workload inserts generated records, it can't run on the -stored patterns.\n\n")
+			DisplayHelp()
+		}
+		if !(*phase == "load" || *phase == "run" || *phase == "both") {
+			DisplayHelp()
+		}
+		if *phase == "run" && len(*sessovrd) == 0 {
+			fmt.Printf("Fatal: -phase run needs the -session loaded by -phase load.\n\n")
+			DisplayHelp()
+		}
+		if core.OperationCount == 0 && *duration < 1 && *phase != "load" && len(*profile) == 0 && len(*search) == 0 {
+			fmt.Printf("Fatal: operationcount=0 needs a -dur.\n\n")
+			DisplayHelp()
+		}
+		// A CORE WORKLOAD USES ONE TABLE OF RECORDCOUNT RECORDS
+		*loops = 1
+		*iterations = int(core.RecordCount)
+	} else if len(*sessovrd) == 0 && !workersMatchLoops(*tpsWorkers, *loops) {
+		// THE LOOPS OF A REUSED SESSION ARE ONLY KNOWN ONCE ITS PARAMETERS ARE LOADED
 		fmt.Printf("Workers: %d    Loops: %d", *tpsWorkers, *loops)
 		fmt.Printf("\nFatal: Incompatible worker and loop count.  Workers must be 1 or equal to the number of loops!\n\n")
 		DisplayHelp()
 	}
 
-	if *tps && *duration < 1 {
+	if *tps && *duration < 1 && len(*profile) == 0 && len(*search) == 0 {
+		DisplayHelp()
+	}
+
+	if *dropSession && len(*sessovrd) == 0 {
+		fmt.Printf("Fatal: -drop needs the -session to drop.\n\n")
+		DisplayHelp()
+	}
+
+	if len(*purgeAge) > 0 {
+		if _, err := time.ParseDuration(*purgeAge); err != nil {
+			fmt.Printf("Fatal: Invalid -purge age: %s\n\n", err)
+			DisplayHelp()
+		}
+	}
+	if *purgeUnregistered && len(*purgeAge) == 0 {
+		fmt.Printf("Fatal: -purge-unregistered needs a -purge age.\n\n")
 		DisplayHelp()
 	}
 
 	arguments := arguments.Arguments{}
+	arguments.Command = command
 	arguments.Parallel = *parallel
 	arguments.Loops = *loops
 	arguments.Iterations = *iterations
@@ -94,152 +364,647 @@ func CommandLineArgs() arguments.Arguments {
 	arguments.NoDataCheck = *nodatacheck
 	arguments.Spatterns = *spatterns
 	arguments.Sessovrd = *sessovrd
+	arguments.Output = *output
+	arguments.OutFile = *outFile
+	arguments.MaxErrorRate = *maxErrorRate
+	if len(*search) > 0 && !isFlagSet("max-error-rate") {
+		// THE STEPS ABOVE THE SUSTAINED RATE ARE EXPECTED TO FAIL, THE SLO JUDGES THEM
+		arguments.MaxErrorRate = -1
+	}
+	arguments.MaxP99 = *maxP99
+	arguments.MinTPS = *minTPS
+	arguments.Cleanup = *cleanup
+	arguments.DropSession = *dropSession
+	arguments.PurgeAge = *purgeAge
+	arguments.PurgeUnregistered = *purgeUnregistered
+	arguments.ListOnly = *listSessions
+	arguments.Seed = *seed
+	arguments.Workload = *coreWorkload
+	arguments.Phase = *phase
+	arguments.Properties = properties
+	arguments.Mix = *mix
+	arguments.Distribution = *dist
+	arguments.ZipfTheta = *zipfTheta
+	arguments.HotFraction = *hotFraction
+	arguments.HotOps = *hotOps
+	arguments.Rate = *rate
+	arguments.Profile = *profile
+	arguments.Search = *search
+	arguments.SearchMode = *searchMode
+	arguments.SearchSteps = *searchSteps
+	arguments.SLOP99 = *sloP99
+	arguments.SLOErrorRate = *sloErrorRate
+	arguments.ReportInterval = *reportInterval
+	arguments.ReportFile = *reportFile
+	arguments.Warmup = *warmup
+	if arguments.Seed == 0 {
+		arguments.Seed = time.Now().UTC().UnixNano()
+	}
 	return arguments
 }
 
-func RandStringBytes(n int) string {
-	rand.Seed(time.Now().UTC().UnixNano())
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = junkBytes[rand.Intn(len(junkBytes))]
+// isFlagSet reports whether the flag was given on the command line.
+func isFlagSet(name string) (set bool) {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// loadSessionParameters copies the dataset parameters of a reused session
+// from its registry record. A parameter given on the command line must match
+// the one the session was created with.
+func loadSessionParameters(info *session.Info, arguments *arguments.Arguments) (err error) {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	parameters := []struct {
+		flag    string
+		current *int
+		stored  int
+	}{
+		{"loops", &arguments.Loops, info.Arguments.Loops},
+		{"iter", &arguments.Iterations, info.Arguments.Iterations},
+		{"kbs", &arguments.KeyBS, info.Arguments.KeyBS},
+		{"dbs", &arguments.DataBS, info.Arguments.DataBS},
 	}
-	return string(b)
+
+	for _, parameter := range parameters {
+		if explicit[parameter.flag] && *parameter.current != parameter.stored {
+			return fmt.Errorf("-%s %d doesn't match the %d session %s was created with", parameter.flag,
+				*parameter.current, parameter.stored, info.Name)
+		}
+		*parameter.current = parameter.stored
+	}
+
+	if len(arguments.Workload) == 0 && !workersMatchLoops(arguments.TpsWorkers, arguments.Loops) {
+		return fmt.Errorf("-tw %d must be 1 or equal to the %d loops of session %s", arguments.TpsWorkers,
+			arguments.Loops, info.Name)
+	}
+
+	return nil
 }
 
-func UniqStringBytes(l int, n int, st string) string {
-	lnth := len(st)
-	uniq := fmt.Sprintf("%d.%d.", l, n)
-	ulen := len(uniq)
-	nstr := fmt.Sprintf("%s%s", uniq, st[0:lnth-ulen])
-	return nstr
+// workersMatchLoops reports whether the TPS workers can share the loops:
+// one worker runs them all, otherwise each worker runs its own loop.
+func workersMatchLoops(workers int, loops int) bool {
+	return workers == 1 || workers == loops
 }
 
-func RandSessBytes(n int) string {
-	rand.Seed(time.Now().UTC().UnixNano())
+func RandSessBytes(random *rand.Rand, n int) string {
 	b := make([]byte, n)
 	for i := range b {
-		b[i] = sessBytes[rand.Intn(len(sessBytes))]
+		b[i] = sessBytes[random.Intn(len(sessBytes))]
 	}
 	return string(b)
 }
 
-func GenerateRandom(l int, n int, dbytes int, kbytes int) {
-	fmt.Printf("Creating data pattern...")
-	for loops := 0; loops < l; loops++ {
-		fmt.Printf("%d.", loops+1)
-		for iter := 0; iter < n; iter++ {
-			JunkKey[loops][iter] = UniqStringBytes(loops, iter, RandStringBytes(kbytes))
-			JunkData[loops][iter] = RandStringBytes(dbytes)
+// scheduledRates describes the rates the corrected latencies are measured
+// against: the -rate, or the rates of the stages of a -profile or -search.
+func scheduledRates(arguments arguments.Arguments, stages []workload.StageResults) string {
+	if len(stages) == 0 {
+		return fmt.Sprintf("-rate %v", arguments.Rate)
+	}
+
+	var rates []string
+	for _, stage := range stages {
+		rate := ""
+		if stage.Stage.Rate > 0 && stage.Stage.Rate != stage.Stage.Target {
+			rate = fmt.Sprintf("%.0f-%.0f", stage.Stage.Rate, stage.Stage.Target)
+		} else if stage.Stage.Rate > 0 {
+			rate = fmt.Sprintf("%.0f", stage.Stage.Rate)
+		} else if arguments.Rate > 0 {
+			// A CONCURRENCY STEP RUNS AT THE -rate
+			rate = fmt.Sprintf("%v", arguments.Rate)
+		}
+		if len(rate) > 0 && (len(rates) == 0 || rates[len(rates)-1] != rate) {
+			rates = append(rates, rate)
 		}
 	}
-	fmt.Printf("  Success.\n")
+	return fmt.Sprintf("stage rates %s ops/sec", strings.Join(rates, ", "))
 }
 
-func InitMem(loops int, iter int) {
-	AvailData = make(map[int]memory.Memory)
-	JunkData = make([][]string, loops)
-	for i := range JunkData {
-		JunkData[i] = make([]string, iter)
+func showStats(arguments arguments.Arguments, results workload.Results, stages []workload.StageResults) {
+	if arguments.TPS || len(arguments.Workload) > 0 || arguments.Command == "load" {
+		fmt.Printf("\n\nRead Errors: %d\n", results.ReadErrors)
+		fmt.Printf("Write Errors: %d\n", results.WriteErrors)
+		fmt.Printf("Verify Errors: %d\n", results.VerifyErrors)
+		fmt.Printf("Total Operations: %d\n", results.Ops)
+		fmt.Printf("Time Elapsed: %d seconds\n", int64(results.Duration.Seconds()))
+		fmt.Printf("TPS Rate: %d\n", int64(results.TPS()))
 	}
 
-	JunkKey = make([][]string, loops)
-	for i := range JunkKey {
-		JunkKey[i] = make([]string, iter)
+	fmt.Printf("\nWrite Statistics (%d errors):\n    %s", results.WriteErrors, results.WriteStats)
+	fmt.Printf("Read Statistics (%d errors):\n    %s", results.ReadErrors, results.ReadStats)
+	fmt.Printf("Verify Statistics (%d errors):\n    %s\n", results.VerifyErrors, results.VerifyStats)
+	for _, name := range results.OpNames() {
+		fmt.Printf("%s Statistics (%d errors):\n    %s", name, results.OpErrors[name], results.OpStats[name])
 	}
+	if results.Corrected.Count() > 0 {
+		fmt.Printf("Corrected Latency (from the intended start, %s):\n    %s", scheduledRates(arguments, stages), results.Corrected)
+		fmt.Printf("Uncorrected Latency (from the actual start):\n    %s", results.Uncorrected)
+	}
+	if warmup := results.Warmup; warmup != nil {
+		fmt.Printf("\nWarm-up (-warmup %s, not counted above): %d operations in %d seconds, %d errors\n    %s",
+			arguments.Warmup, warmup.Ops, int64(warmup.Duration.Seconds()), warmup.ReadErrors+warmup.WriteErrors+warmup.VerifyErrors,
+			warmup.Latency())
+	}
+	fmt.Printf("\n")
+	fmt.Printf("Seed: %d (replay this run with -seed %d)\n\n", arguments.Seed, arguments.Seed)
+}
+
+// checkThresholds returns a description of every -max-error-rate, -max-p99
+// and -min-tps threshold the results breach.
+func checkThresholds(arguments arguments.Arguments, results workload.Results) (violations []string) {
+	errors := results.ReadErrors + results.WriteErrors + results.VerifyErrors
+	if arguments.MaxErrorRate >= 0 && errors > 0 && (results.ErrorRate() > arguments.MaxErrorRate || results.Ops <= 0) {
+		violations = append(violations, fmt.Sprintf("Error rate %.6f (%d errors) is above -max-error-rate %.6f", results.ErrorRate(),
+			errors, arguments.MaxErrorRate))
+	}
+
+	if maxP99 := timeparse.ParseDuration(arguments.MaxP99); maxP99 > 0 {
+		if p99 := results.WriteStats.Percentile(99); p99 > maxP99 {
+			violations = append(violations, fmt.Sprintf("Write p99 %v is above -max-p99 %v", p99, maxP99))
+		}
+		if p99 := results.ReadStats.Percentile(99); p99 > maxP99 {
+			violations = append(violations, fmt.Sprintf("Read p99 %v is above -max-p99 %v", p99, maxP99))
+		}
+		if p99 := results.Corrected.Percentile(99); p99 > maxP99 {
+			violations = append(violations, fmt.Sprintf("Corrected p99 %v is above -max-p99 %v", p99, maxP99))
+		}
+	}
+
+	if arguments.MinTPS > 0 && results.TPS() < arguments.MinTPS {
+		violations = append(violations, fmt.Sprintf("Throughput %.2f ops/sec is below -min-tps %.2f", results.TPS(), arguments.MinTPS))
+	}
+
+	return violations
+}
+
+// runProfile runs a test once per stage of the -profile, or once per rate
+// tried by the -search.
+func runProfile(ctx context.Context, engine *workload.Engine, arguments arguments.Arguments,
+	run func(ctx context.Context) (workload.Results, error)) (results workload.Results, stages []workload.StageResults, err error) {
+	if len(arguments.Search) > 0 {
+		slo := workload.SLO{P99: timeparse.ParseDuration(arguments.SLOP99), ErrorRate: arguments.SLOErrorRate}
+		search, err := workload.NewSearch(arguments.Search, arguments.SearchMode, arguments.SearchSteps, slo)
+		if err != nil {
+			return results, nil, err
+		}
+		return engine.SearchRate(ctx, search, run)
+	}
+
+	profile, err := workload.ParseProfile(arguments.Profile)
+	if err != nil {
+		return results, nil, err
+	}
+	return engine.RunProfile(ctx, profile, run)
+}
+
+// openSeries creates the -report-file and makes the engine print and write
+// every -report-interval to it.
+func openSeries(engine *workload.Engine, arguments arguments.Arguments) (series *report.Series, err error) {
+	filename := arguments.ReportFile
+	if len(filename) == 0 {
+		filename = fmt.Sprintf("benchmark_db_%s_intervals.csv", SessionName)
+	}
+	if series, err = report.NewSeries(filename, time.Now()); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Interval results written to %s\n", filename)
+
+	started := time.Now()
+	interval, _ := time.ParseDuration(arguments.ReportInterval)
+	engine.ReportIntervals(interval, func(end time.Time, length time.Duration, results *workload.Results, warmup *workload.Results) {
+		for _, phase := range []struct {
+			name    string
+			results *workload.Results
+		}{{"warmup", warmup}, {"measured", results}} {
+			// AN INTERVAL IS ONLY SHOWN AS WARM-UP WHEN IT HAS NO MEASURED OPERATIONS
+			if (phase.name == "warmup" && phase.results.Ops == 0) || (phase.name == "measured" && phase.results.Ops == 0 && warmup.Ops > 0) {
+				continue
+			}
+			latency := phase.results.Latency()
+			errors := phase.results.ReadErrors + phase.results.WriteErrors + phase.results.VerifyErrors
+			fmt.Printf("\n[%7.1fs] %9d ops %10.0f ops/sec %6d errors   p50 %-12v p99 %-12v max %v", end.Sub(started).Seconds(),
+				phase.results.Ops, phase.results.TPS(), errors, latency.Percentile(50), latency.Percentile(99), latency.Max())
+			if phase.name == "warmup" {
+				fmt.Printf(" (warm-up)")
+			}
+			if err := series.Add(end, length, phase.name, phase.results.Ops, errors, latency); err != nil {
+				fmt.Printf("\nFailed to write the -report-file: %s", err)
+			}
+		}
+	})
+	return series, nil
+}
+
+// showStages prints the throughput and latency of every -profile stage.
+func showStages(arguments arguments.Arguments, stages []workload.StageResults) {
+	if len(stages) == 0 {
+		return
+	}
+
+	if len(arguments.Search) > 0 {
+		fmt.Printf("Search %s (%s, SLO: p99 %s, error rate %v):\n", arguments.Search, arguments.SearchMode, arguments.SLOP99,
+			arguments.SLOErrorRate)
+	} else {
+		fmt.Printf("Profile %s:\n", arguments.Profile)
+	}
+	fmt.Printf("%-6s %-40s %10s %10s %8s %12s %12s %12s  %s\n", "Stage", "Load", "Ops", "Ops/sec", "Errors", "p50", "p99", "Max", "SLO")
+	for j, stage := range stages {
+		latency := stage.Results.Latency()
+		fmt.Printf("%-6d %-40s %10d %10.0f %8d %12v %12v %12v  %s\n", j+1, stage.Stage, stage.Results.Ops, stage.Results.TPS(),
+			stage.Results.ReadErrors+stage.Results.WriteErrors+stage.Results.VerifyErrors, latency.Percentile(50),
+			latency.Percentile(99), latency.Max(), stage.SLO)
+	}
+	if len(arguments.Search) > 0 {
+		if rate := workload.MaxSustainedRate(stages); rate > 0 {
+			fmt.Printf("Maximum sustainable throughput: %.0f ops/sec\n", rate)
+		} else {
+			fmt.Printf("Maximum sustainable throughput: none of the rates tried met the SLO\n")
+		}
+	}
+	fmt.Printf("\n")
+}
+
+// addLatencies adds the latencies of the results under their report names.
+func addLatencies(add func(name string, stats *statistics.DurationSet), results workload.Results) {
+	add("write", results.WriteStats)
+	add("read", results.ReadStats)
+	add("verify", results.VerifyStats)
+	for _, name := range results.OpNames() {
+		add(strings.ToLower(name), results.OpStats[name])
+	}
+	if results.Corrected.Count() > 0 {
+		add("corrected", results.Corrected)
+		add("uncorrected", results.Uncorrected)
+	}
+}
+
+func saveResults(config config.Config, arguments arguments.Arguments, started time.Time, results workload.Results, stages []workload.StageResults, violations []string) {
+	doc := report.New(SessionName, config, arguments, started)
+	doc.SetTotals(results.Ops, results.Duration, results.ReadErrors, results.WriteErrors)
+	doc.VerifyErrors = results.VerifyErrors
+	doc.Violations = violations
+	addLatencies(doc.AddLatency, results)
+	if warmup := results.Warmup; warmup != nil {
+		doc.WarmupOperations = warmup.Ops
+		doc.WarmupErrors = warmup.ReadErrors + warmup.WriteErrors + warmup.VerifyErrors
+		doc.WarmupElapsed = warmup.Duration.Seconds()
+		addLatencies(func(name string, stats *statistics.DurationSet) { doc.AddLatency("warmup_"+name, stats) }, *warmup)
+	}
+	for _, stage := range stages {
+		stageReport := report.Stage{
+			Rate:       stage.Stage.Rate,
+			Target:     stage.Stage.Target,
+			Workers:    stage.Stage.Workers,
+			Hold:       stage.Stage.Hold.Seconds(),
+			Operations: stage.Results.Ops,
+			Errors:     stage.Results.ReadErrors + stage.Results.WriteErrors + stage.Results.VerifyErrors,
+			Elapsed:    stage.Results.Duration.Seconds(),
+			Throughput: stage.Results.TPS(),
+			SLO:        stage.SLO,
+		}
+		addLatencies(stageReport.AddLatency, stage.Results)
+		doc.AddStage(stageReport)
+	}
+	doc.MaxSustained = workload.MaxSustainedRate(stages)
+	if err := doc.Save(); err != nil {
+		fmt.Printf("Failed to save the results: %s\n", err)
+	}
+}
+
+// handleSignals cancels the test on the first SIGINT/SIGTERM so that the
+// workers stop and the partial results are reported. A second signal
+// exits immediately.
+func handleSignals(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		fmt.Printf("\n%s received: stopping the test and reporting the results collected so far...\n", sig)
+		cancel()
+		<-signals
+		fmt.Printf("\nAborted.\n")
+		os.Exit(ExitInterrupted)
+	}()
 }
 
 func main() {
+	os.Exit(runBenchmark())
+}
+
+// runBenchmark runs the whole test and returns the process exit code, so
+// that the deferred cleanup always runs before the process exits.
+func runBenchmark() int {
 	// LOAD THE CONFIG AND PROCESS COMMAND LINE ARGUMENTS
 	var config config.Config = config.ReadConfig(configfile)
 	var arguments arguments.Arguments = CommandLineArgs()
 
 	// CREATE A SESSION NAME
 	if i := len(arguments.Sessovrd); i == 0 {
-		SessionName = RandSessBytes(10)
+		SessionName = RandSessBytes(rand.New(rand.NewSource(arguments.Seed)), 10)
 	} else {
 		SessionName = arguments.Sessovrd
 	}
 
 	// SETUP THE DATABASE INTERFACE
 	var idb db.Interface_DB
+	var engine *workload.Engine
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var results workload.Results
+	var stages []workload.StageResults
+	var loaded bool
 	var err error
-	var read_stats *statistics.DurationSet
-	var write_stats *statistics.DurationSet
-
-	read_stats = &statistics.DurationSet{}
-	write_stats = &statistics.DurationSet{}
 
 	db.Init(config)
 
-	// INITIALIZE THE MEMORY
-	InitMem(arguments.Loops, arguments.Iterations)
-
 	var wg sync.WaitGroup
 
-	// SET THE COUNTERS
-	WriteErrors = 0
-	ReadErrors = 0
-
 	// SHOW THE PROGRAM AND TEST INFO
 	fmt.Printf("Benchmark DB Tool - Version %s (%s)\n", version.VERSION, version.BUILDID)
-	fmt.Printf("Mode: %s, Iterations: %d, Key Size: %d bytes, Data Size: %d bytes Session-ID: %s\n", arguments.Mode,
-		arguments.Iterations, arguments.KeyBS, arguments.DataBS, SessionName)
+	fmt.Printf("Mode: %s, Iterations: %d, Key Size: %d bytes, Data Size: %d bytes Session-ID: %s Seed: %d\n", arguments.Mode,
+		arguments.Iterations, arguments.KeyBS, arguments.DataBS, SessionName, arguments.Seed)
 
 	if idb, err = db.Get(arguments.DB_Type); err != nil {
 		fmt.Printf("Database %s doesn't exist\n", arguments.DB_Type)
-		os.Exit(1)
+		return ExitUsage
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	handleSignals(cancel)
+
+	if err = idb.Connect(ctx, config, arguments); err != nil {
+		fmt.Printf("Fatal: failed to connect to the %s database: %s\n", arguments.DB_Type, err)
+		return ExitConnect
 	}
+	defer idb.Close()
 
-	if err = idb.Connect(config, arguments); err != nil {
-		os.Exit(1)
+	// STANDALONE CLEANUP MODES
+	if arguments.DropSession {
+		if err = session.Drop(ctx, idb, SessionName, arguments.Loops); err != nil {
+			fmt.Printf("Failed to drop session %s: %s\n", SessionName, err)
+			return ExitSchema
+		}
+		return ExitSuccess
+	}
+	if arguments.ListOnly {
+		sessions, err := session.List(ctx, idb)
+		if err != nil {
+			fmt.Printf("Failed to list sessions: %s\n", err)
+			return ExitConnect
+		}
+		session.Print(sessions)
+		return ExitSuccess
+	}
+	if len(arguments.PurgeAge) > 0 {
+		age, _ := time.ParseDuration(arguments.PurgeAge)
+		if _, err = session.Purge(ctx, idb, age, arguments.PurgeUnregistered); err != nil {
+			fmt.Printf("Failed to purge sessions: %s\n", err)
+			return ExitSchema
+		}
+		return ExitSuccess
 	}
 
-	// GENERATE SOME RANDOM DATA AND SAVE TO MEMORY OR LOAD EXISTING PATTERNS FROM DATABASE
+	// LOAD THE PARAMETERS OF A REUSED SESSION
+	if len(arguments.Sessovrd) > 0 {
+		info, err := session.Find(ctx, idb, SessionName)
+		if err != nil {
+			fmt.Printf("Failed to read the session registry: %s\n", err)
+			return ExitConnect
+		}
+		if info == nil && arguments.Command == "run" {
+			fmt.Printf("Fatal: session %s isn't registered, load it first with: benchmark_db load\n", SessionName)
+			return ExitUsage
+		} else if info == nil {
+			fmt.Printf("Warning: session %s isn't registered, using the command line parameters\n", SessionName)
+			if len(arguments.Workload) == 0 && !workersMatchLoops(arguments.TpsWorkers, arguments.Loops) {
+				fmt.Printf("Fatal: -tw %d must be 1 or equal to the %d -loops\n", arguments.TpsWorkers, arguments.Loops)
+				return ExitUsage
+			}
+		} else {
+			if err = loadSessionParameters(info, &arguments); err != nil {
+				fmt.Printf("Fatal: %s\n", err)
+				return ExitUsage
+			}
+			fmt.Printf("Reusing session %s\n", info)
+			loaded = !info.Loaded.IsZero()
+			if !loaded && arguments.Command == "run" {
+				fmt.Printf("Warning: session %s wasn't loaded completely, reads may miss records\n", SessionName)
+			}
+
+			// THE STORED PATTERNS DEFAULT TO THE TABLES OF THE SESSION
+			if arguments.Spatterns && len(config.Patterns) == 0 {
+				for j := 0; j < info.Tables; j++ {
+					config.Patterns = append(config.Patterns, fmt.Sprintf("benchmark_db_%s%d", SessionName, j))
+				}
+			}
+		}
+	}
+
+	var coreWorkload *workload.CoreWorkload
+	if len(arguments.Workload) > 0 {
+		coreWorkload, err = workload.NewCoreWorkload(arguments.Workload, arguments.Properties, int64(arguments.Iterations),
+			workload.DistributionOptions(arguments))
+		if err == nil && coreWorkload.RecordCount != int64(arguments.Iterations) {
+			err = fmt.Errorf("recordcount %d doesn't match the %d records of session %s", coreWorkload.RecordCount,
+				arguments.Iterations, SessionName)
+		}
+		if err != nil {
+			fmt.Printf("Fatal: %s\n", err)
+			return ExitUsage
+		}
+	}
+
+	// GENERATE THE DATA ON DEMAND FROM THE SESSION NAME OR LOAD EXISTING PATTERNS FROM DATABASE
+	var data dataset.Dataset
+	var stored *dataset.Stored
 	if arguments.Spatterns {
-		JunkKey, JunkData, AvailData, _ = idb.ReadPatternData(SessionName, config, arguments)
+		JunkKey, JunkData, err := idb.ReadPatternData(ctx, SessionName, config, arguments)
+		if err != nil {
+			fmt.Printf("Failed to read the stored patterns: %s\n", err)
+			return ExitSchema
+		}
+		stored = dataset.NewStored(JunkKey, JunkData)
+		data = stored
 	} else {
-		GenerateRandom(arguments.Loops, arguments.Iterations, arguments.DataBS, arguments.KeyBS)
+		data = dataset.NewGenerator(dataset.SessionSeed(SessionName), arguments.KeyBS, arguments.DataBS)
 	}
 
 	// CREATE TEST TABLES IF NOT USING AN EXISTING SESSION
+	created := session.New(SessionName, arguments)
 	if i := len(arguments.Sessovrd); i == 0 {
-		if err = idb.CreateTestTables(SessionName, arguments.Loops); err != nil {
-			os.Exit(2)
+		if err = idb.CreateTestTables(ctx, SessionName, arguments.Loops); err != nil {
+			return ExitSchema
+		}
+		if err = session.Register(ctx, idb, created); err != nil {
+			fmt.Printf("Warning: failed to register session %s: %s\n", SessionName, err)
 		}
 	}
 
+	engine = workload.New(idb, SessionName, config, arguments, data)
+
+	// STORED PATTERNS AND THE RECORDS OF A LOADED SESSION ARE AVAILABLE TO READ FROM THE START
+	if stored != nil {
+		for loop := 0; loop < arguments.Loops; loop++ {
+			for iter := 0; iter < arguments.Iterations && iter < stored.Records(loop); iter++ {
+				engine.MarkWritten(loop, iter)
+			}
+		}
+	} else if loaded {
+		for loop := 0; loop < arguments.Loops; loop++ {
+			for iter := 0; iter < arguments.Iterations; iter++ {
+				engine.MarkWritten(loop, iter)
+			}
+		}
+	}
+
+	// REPORT THE INTERVALS WHILE THE TESTS RUN
+	if len(arguments.ReportInterval) > 0 {
+		series, err := openSeries(engine, arguments)
+		if err != nil {
+			fmt.Printf("Failed to create the -report-file: %s\n", err)
+			return ExitUsage
+		}
+		defer series.Close()
+	}
+
 	// DO THE TESTS
-	if arguments.TPS {
-		wg.Add(1)
+	StartTest := time.Now()
+	if coreWorkload != nil {
+		var load workload.Results
+		if arguments.Phase != "run" {
+			if load, err = engine.YCSBLoad(ctx, coreWorkload); err == nil {
+				results = load
+			}
+		}
+		if arguments.Phase != "load" && err == nil && ctx.Err() == nil {
+			StartTest = time.Now()
+			run := func(ctx context.Context) (workload.Results, error) { return engine.YCSBRun(ctx, coreWorkload) }
+			if len(arguments.Profile) > 0 || len(arguments.Search) > 0 {
+				results, stages, err = runProfile(ctx, engine, arguments, run)
+			} else {
+				results, err = run(ctx)
+			}
+			if err == nil && arguments.Phase == "both" {
+				// THE LOAD PHASE IS REPORTED NEXT TO THE RUN PHASE OPERATIONS
+				results.Op(workload.OpLoad).Merge(load.Op(workload.OpInsert))
+				results.OpErrors[workload.OpLoad] = load.OpErrors[workload.OpInsert]
+			}
+		}
+		if err != nil {
+			fmt.Printf("Workload failed: %s\n", err)
+			return ExitUsage
+		}
+	} else if arguments.TPS {
+		var run func(ctx context.Context) (workload.Results, error)
 		if arguments.Mode == "rw" {
-			idb.TPSTestRW(SessionName, config, arguments, &wg, JunkData, JunkKey, AvailData, read_stats, write_stats)
+			run = engine.TPSTestRW
 		}
 		if arguments.Mode == "w" {
-			idb.TPSTestW(SessionName, config, arguments, &wg, JunkData, JunkKey, AvailData, read_stats, write_stats)
+			run = engine.TPSTestW
 		}
 		if arguments.Mode == "r" {
-			idb.TPSTestR(SessionName, config, arguments, &wg, JunkData, JunkKey, AvailData, read_stats, write_stats)
+			run = engine.TPSTestR
+		}
+		if len(arguments.Profile) > 0 || len(arguments.Search) > 0 {
+			// THE SAMPLE RECORDS OF THE READ TEST ARE WRITTEN BEFORE THE FIRST STAGE STARTS
+			var prepare *workload.Results
+			if arguments.Mode == "r" {
+				prepare = engine.PrepareTPSTestR(ctx)
+			}
+			if results, stages, err = runProfile(ctx, engine, arguments, run); err == nil && prepare != nil {
+				results.WriteErrors = results.WriteErrors + prepare.WriteErrors
+				results.WriteStats.Merge(prepare.WriteStats)
+			}
+		} else {
+			results, err = run(ctx)
+		}
+		if err != nil {
+			fmt.Printf("TPS test failed: %s\n", err)
+			return ExitUsage
+		}
+	} else if arguments.Command == "load" {
+		if results, err = engine.Load(ctx); err != nil {
+			fmt.Printf("Load failed: %s\n", err)
+			return ExitUsage
 		}
 	} else {
+		results = *workload.NewResults()
 
 		for loops := 0; loops < arguments.Loops; loops++ {
 
+			if ctx.Err() != nil {
+				break
+			}
+
 			if arguments.Parallel {
 				time.Sleep(time.Millisecond * 200)
 				wg.Add(1)
-				go idb.TestCycle(SessionName, config, arguments, loops, &wg, JunkData, JunkKey, read_stats, write_stats)
+				go engine.TestCycle(ctx, loops, &wg, results.ReadStats, results.WriteStats, results.VerifyStats)
 			} else {
 				wg.Add(1)
-				idb.TestCycle(SessionName, config, arguments, loops, &wg, JunkData, JunkKey, read_stats, write_stats)
+				engine.TestCycle(ctx, loops, &wg, results.ReadStats, results.WriteStats, results.VerifyStats)
 			}
 		}
+
+		// WAIT FOR DATABASE ACTIVITY TO CEASE
+		wg.Wait()
+
+		results.Ops = int64(results.WriteStats.Count() + results.ReadStats.Count())
+		results.Duration = time.Since(StartTest)
+		results.ReadErrors = engine.GetReadErrors()
+		results.WriteErrors = engine.GetWriteErrors()
+		results.VerifyErrors = engine.GetVerifyErrors()
+	}
+
+	// A COMPLETE LOAD MAKES THE SESSION AVAILABLE TO THE run COMMAND
+	if arguments.Command == "load" && ctx.Err() == nil && results.WriteErrors == 0 {
+		created.Loaded = time.Now().UTC()
+		if err = session.Register(ctx, idb, created); err != nil {
+			fmt.Printf("Warning: failed to register session %s: %s\n", SessionName, err)
+		}
+		fmt.Printf("Session %s loaded, benchmark it with: benchmark_db run -session %s\n", SessionName, SessionName)
 	}
 
-	// WAIT FOR DATABASE ACTIVITY TO CEASE
-	wg.Wait()
+	// PRINT THE TIME RESULTS AND SAVE THE MACHINE READABLE RESULTS
+	violations := checkThresholds(arguments, results)
+	showStats(arguments, results, stages)
+	showStages(arguments, stages)
+	saveResults(config, arguments, StartTest, results, stages, violations)
+
+	// DROP THE SESSION, WITH A FRESH CONTEXT AS THE TEST ONE IS CANCELLED WHEN INTERRUPTED
+	cleanupFailed := false
+	if arguments.Cleanup {
+		if err = session.Drop(context.Background(), idb, SessionName, arguments.Loops); err != nil {
+			fmt.Printf("Failed to drop session %s: %s\n", SessionName, err)
+			cleanupFailed = true
+		}
+	}
 
-	// PRINT THE TIME RESULTS
-	fmt.Printf("\nWrite Statistics (%d errors):\n    %s", idb.GetWriteErrors(), write_stats)
-	fmt.Printf("Read Statistics (%d errors):\n    %s\n\n", idb.GetReadErrors(), read_stats)
+	if results.VerifyErrors > 0 {
+		fmt.Printf("FAILED: %d reads did not match the data written\n", results.VerifyErrors)
+		return ExitVerify
+	}
+	if ctx.Err() != nil {
+		fmt.Printf("INTERRUPTED: the results above are partial\n")
+		return ExitInterrupted
+	}
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			fmt.Printf("FAILED: %s\n", violation)
+		}
+		return ExitThreshold
+	}
+	if cleanupFailed {
+		return ExitSchema
+	}
 
+	return ExitSuccess
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 )
 
 type Config struct {
@@ -14,6 +16,77 @@ type Config struct {
 	Username     string
 	Password     string
 	Patterns     []string
+	MySQL        MySQL
+	MongoDB      MongoDB
+	Embedded     Embedded
+	SQLite       SQLite
+	Redis        Redis
+}
+
+// MySQL is the section of the mysql driver. The DSN is a go-sql-driver DSN
+// (user:password@tcp(host:3306)/dbname), the storage engine and charset of
+// the test tables default to those of the server.
+type MySQL struct {
+	DSN     string
+	Engine  string
+	Charset string
+}
+
+// MongoDB is the section of the mongodb driver. The URI is a mongodb://
+// connection string and the database defaults to benchmark_db. The write
+// concern is majority, a number of nodes or a tag set, Journal waits for the
+// journal, and the read preference is primary (the default),
+// primaryPreferred, secondary, secondaryPreferred or nearest. Settings given
+// here override those of the URI.
+type MongoDB struct {
+	URI            string
+	Database       string
+	WriteConcern   string
+	Journal        bool
+	ReadPreference string
+}
+
+// Embedded is the section of the bbolt, badger and pebble drivers. They keep
+// their files in Directory (default benchmark_db_data): benchmark_db.bolt and
+// the badger and pebble subdirectories. Sync makes every write wait for the
+// data to be synced to disk.
+type Embedded struct {
+	Directory string
+	Sync      bool
+}
+
+// SQLite is the section of the sqlite driver. Path is the database file
+// (default benchmark_db.sqlite), JournalMode is WAL, DELETE, MEMORY,
+// TRUNCATE, PERSIST or OFF, Synchronous is OFF, NORMAL, FULL or EXTRA and
+// CacheSize is the page cache in pages, or in KiB when negative, as the
+// cache_size pragma. Empty or zero settings keep the SQLite defaults.
+type SQLite struct {
+	Path        string
+	JournalMode string
+	Synchronous string
+	CacheSize   int
+}
+
+// Redis is the section of the redis driver. Mode is standalone, sentinel or
+// cluster (the default). Addrs are the server, the sentinels or the cluster
+// seed nodes and default to Clusternodes, Password defaults to the Password
+// of the config. MasterName is the master monitored by the sentinels and DB
+// the database index, which a cluster doesn't have. Replica reads from the
+// replicas of a sentinel or cluster setup. TLS connects with TLS, verified
+// against the CA certificates of TLSCAFile if given, otherwise against the
+// system ones.
+type Redis struct {
+	Mode                  string
+	Addrs                 []string
+	MasterName            string
+	DB                    int
+	Username              string
+	Password              string
+	SentinelPassword      string
+	Replica               bool
+	TLS                   bool
+	TLSCAFile             string
+	TLSInsecureSkipVerify bool
 }
 
 func ReadConfig(filename string) Config {
@@ -26,3 +99,42 @@ func ReadConfig(filename string) Config {
 	}
 	return config
 }
+
+var passwordRegex = regexp.MustCompile(`(?i)(password=)('[^']*'|[^ ]*)`)
+var userinfoRegex = regexp.MustCompile(`(://[^:/@]*:)[^@]*@`)
+
+// Redacted returns a copy of the configuration with the secrets removed so
+// that it can be written along with the results.
+func (config Config) Redacted() Config {
+	config.Password = ""
+	config.PSQL = RedactDSN(config.PSQL)
+	config.MySQL.DSN = RedactMySQLDSN(config.MySQL.DSN)
+	config.MongoDB.URI = RedactDSN(config.MongoDB.URI)
+	config.Redis.Password = ""
+	config.Redis.SentinelPassword = ""
+	return config
+}
+
+func RedactDSN(dsn string) string {
+	dsn = passwordRegex.ReplaceAllString(dsn, "${1}xxxxx")
+	return userinfoRegex.ReplaceAllString(dsn, "${1}xxxxx@")
+}
+
+// RedactMySQLDSN removes the password of a user:password@tcp(host)/dbname
+// DSN. The password ends at the last '@' before the database name, as the
+// driver parses it, since it may contain '@' itself.
+func RedactMySQLDSN(dsn string) string {
+	slash := strings.LastIndexByte(dsn, '/')
+	if slash < 0 {
+		return dsn
+	}
+	at := strings.LastIndexByte(dsn[:slash], '@')
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.IndexByte(dsn[:at], ':')
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + "xxxxx" + dsn[at:]
+}
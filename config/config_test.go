@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestRedactMySQLDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		redacted string
+	}{
+		{"bench:secret@tcp(127.0.0.1:3306)/bench", "bench:xxxxx@tcp(127.0.0.1:3306)/bench"},
+		{"bench:p@ss@tcp(db:3306)/bench", "bench:xxxxx@tcp(db:3306)/bench"},
+		{"bench:p@ss:w@rd@unix(/tmp/mysql.sock)/bench", "bench:xxxxx@unix(/tmp/mysql.sock)/bench"},
+		{"bench:p@ss@/bench", "bench:xxxxx@/bench"},
+		{"bench:secret@tcp(db)/bench?tls=custom&x=a@b", "bench:xxxxx@tcp(db)/bench?tls=custom&x=a@b"},
+		{"bench@tcp(db)/bench", "bench@tcp(db)/bench"},
+		{"tcp(db:3306)/bench", "tcp(db:3306)/bench"},
+		{"/bench", "/bench"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if redacted := RedactMySQLDSN(test.dsn); redacted != test.redacted {
+			t.Errorf("RedactMySQLDSN(%q) = %q, want %q", test.dsn, redacted, test.redacted)
+		}
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		redacted string
+	}{
+		{"host=db user=bench password=secret dbname=bench", "host=db user=bench password=xxxxx dbname=bench"},
+		{"host=db password='se cret' dbname=bench", "host=db password=xxxxx dbname=bench"},
+		{"postgres://bench:secret@db/bench", "postgres://bench:xxxxx@db/bench"},
+		{"mongodb://bench@db", "mongodb://bench@db"},
+	}
+
+	for _, test := range tests {
+		if redacted := RedactDSN(test.dsn); redacted != test.redacted {
+			t.Errorf("RedactDSN(%q) = %q, want %q", test.dsn, redacted, test.redacted)
+		}
+	}
+}
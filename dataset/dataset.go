@@ -0,0 +1,112 @@
+package dataset
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// WARNING - In junkBytes Don't use ":" for random data generation.  ":" is reserved for redis functions
+
+const junkBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()=+-;~/?<>[]{}_"
+
+// Dataset supplies the key and the value of every (loop, iter) position of
+// a test.
+type Dataset interface {
+	Key(loop int, iter int) string
+	Data(loop int, iter int) string
+}
+
+// Generator computes every key and value from the seed and the position, so
+// any value can be regenerated (and a read verified) without keeping the
+// dataset in memory.
+type Generator struct {
+	seed      uint64
+	keyBytes  int
+	dataBytes int
+}
+
+func NewGenerator(seed int64, keyBytes int, dataBytes int) *Generator {
+	var tmp Generator = Generator{}
+	tmp.seed = uint64(seed)
+	tmp.keyBytes = keyBytes
+	tmp.dataBytes = dataBytes
+	return &tmp
+}
+
+// SessionSeed derives the generator seed from the session name, so that a
+// reused session regenerates the data it was created with.
+func SessionSeed(SessionName string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(SessionName))
+	return int64(hash.Sum64())
+}
+
+// Key starts with "<loop>.<iter>." to keep the keys unique and is padded
+// with random bytes up to the key size.
+func (generator *Generator) Key(loop int, iter int) string {
+	prefix := fmt.Sprintf("%d.%d.", loop, iter)
+	if len(prefix) >= generator.keyBytes {
+		return prefix
+	}
+	return prefix + generator.fill(loop, iter, 0, generator.keyBytes-len(prefix))
+}
+
+func (generator *Generator) Data(loop int, iter int) string {
+	return generator.fill(loop, iter, 1, generator.dataBytes)
+}
+
+func (generator *Generator) fill(loop int, iter int, stream uint64, n int) string {
+	state := mix64(generator.seed)
+	state = mix64(state + uint64(loop))
+	state = mix64(state + uint64(iter))
+	state = mix64(state + stream)
+
+	b := make([]byte, n)
+	for i := 0; i < n; {
+		state += 0x9e3779b97f4a7c15
+		value := mix64(state)
+		for j := 0; j < 8 && i < n; j++ {
+			b[i] = junkBytes[(value&0xff)%uint64(len(junkBytes))]
+			value >>= 8
+			i++
+		}
+	}
+	return string(b)
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(value uint64) uint64 {
+	value = (value ^ (value >> 30)) * 0xbf58476d1ce4e5b9
+	value = (value ^ (value >> 27)) * 0x94d049bb133111eb
+	return value ^ (value >> 31)
+}
+
+// Stored holds the pattern data read from the tables listed in the config
+// file, one loop per pattern table.
+type Stored struct {
+	keys [][]string
+	data [][]string
+}
+
+func NewStored(keys [][]string, data [][]string) *Stored {
+	var tmp Stored = Stored{}
+	tmp.keys = keys
+	tmp.data = data
+	return &tmp
+}
+
+func (stored *Stored) Key(loop int, iter int) string {
+	return stored.keys[loop][iter]
+}
+
+func (stored *Stored) Data(loop int, iter int) string {
+	return stored.data[loop][iter]
+}
+
+// Records is the number of records read from the pattern table of a loop.
+func (stored *Stored) Records(loop int) int {
+	if loop >= len(stored.keys) {
+		return 0
+	}
+	return len(stored.keys[loop])
+}
@@ -0,0 +1,105 @@
+package dataset
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Written records which positions of the dataset hold data, with one bit
+// per (loop, iter) position, so that reads can be limited to data that was
+// written.
+type Written struct {
+	words      []uint64
+	iterations int
+	size       int64
+	count      int64
+	mutex      sync.Mutex
+}
+
+func NewWritten(loops int, iterations int) *Written {
+	var tmp Written = Written{}
+	tmp.iterations = iterations
+	tmp.size = int64(loops) * int64(iterations)
+	tmp.words = make([]uint64, (tmp.size+63)/64)
+	return &tmp
+}
+
+// Size is the number of positions, written or not.
+func (written *Written) Size() int64 {
+	return written.size
+}
+
+// Count is the number of positions written.
+func (written *Written) Count() int64 {
+	written.mutex.Lock()
+	defer written.mutex.Unlock()
+	return written.count
+}
+
+func (written *Written) Set(loop int, iter int) {
+	position := int64(loop)*int64(written.iterations) + int64(iter)
+	if position < 0 || position >= written.size {
+		return
+	}
+
+	written.mutex.Lock()
+	defer written.mutex.Unlock()
+	bit := uint64(1) << uint(position%64)
+	if written.words[position/64]&bit == 0 {
+		written.words[position/64] |= bit
+		written.count++
+	}
+}
+
+func (written *Written) Clear(loop int, iter int) {
+	position := int64(loop)*int64(written.iterations) + int64(iter)
+	if position < 0 || position >= written.size {
+		return
+	}
+
+	written.mutex.Lock()
+	defer written.mutex.Unlock()
+	bit := uint64(1) << uint(position%64)
+	if written.words[position/64]&bit != 0 {
+		written.words[position/64] &^= bit
+		written.count--
+	}
+}
+
+func (written *Written) IsSet(loop int, iter int) bool {
+	position := int64(loop)*int64(written.iterations) + int64(iter)
+	if position < 0 || position >= written.size {
+		return false
+	}
+
+	written.mutex.Lock()
+	defer written.mutex.Unlock()
+	return written.words[position/64]&(uint64(1)<<uint(position%64)) != 0
+}
+
+// Find returns the first written position at or after from, wrapping around
+// at the end. Called with a random from it picks a random written position.
+func (written *Written) Find(from int64) (loop int, iter int, ok bool) {
+	written.mutex.Lock()
+	defer written.mutex.Unlock()
+
+	if written.count == 0 || from < 0 || from >= written.size {
+		return 0, 0, false
+	}
+
+	word := from / 64
+	mask := ^uint64(0) << uint(from%64)
+	for j := int64(0); j <= int64(len(written.words)); j++ {
+		index := (word + j) % int64(len(written.words))
+		word_bits := written.words[index] & mask
+		mask = ^uint64(0)
+		for word_bits != 0 {
+			position := index*64 + int64(bits.TrailingZeros64(word_bits))
+			if position < written.size {
+				return int(position / int64(written.iterations)), int(position % int64(written.iterations)), true
+			}
+			word_bits &= word_bits - 1
+		}
+	}
+	return 0, 0, false
+}
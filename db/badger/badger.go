@@ -0,0 +1,228 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"path/filepath"
+	"strings"
+)
+
+// The records of a loop are the keys under its prefix, the sessions those
+// under the prefix of the registry.
+const sessionPrefix = "benchmark_db_sessions:"
+
+type BadgerDB struct {
+	session *badger.DB
+}
+
+func New() *BadgerDB {
+	var tmp BadgerDB = BadgerDB{}
+	return &tmp
+}
+
+func (db *BadgerDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	directory := config.Embedded.Directory
+	if len(directory) == 0 {
+		directory = "benchmark_db_data"
+	}
+	directory = filepath.Join(directory, "badger")
+
+	fmt.Printf("badger directory: %s (sync: %t)\n", directory, config.Embedded.Sync)
+	options := badger.DefaultOptions(directory).WithSyncWrites(config.Embedded.Sync).WithLoggingLevel(badger.WARNING)
+	if db.session, err = badger.Open(options); err != nil {
+		fmt.Printf("Failed to open the database: '%s'\n", err.Error())
+		return err
+	}
+
+	fmt.Printf("Connection to database was successful!\n")
+
+	return nil
+}
+
+func prefix(SessionName string, loop int) string {
+	return fmt.Sprintf("benchmark_db_%s%d:", SessionName, loop)
+}
+
+// scan returns the keys, without the prefix, and the values of up to count
+// records under the prefix from the start key on, all of them when count
+// is negative.
+func (db *BadgerDB) scan(prefix string, startKey string, count int) (keys []string, values []string, err error) {
+	err = db.session.View(func(txn *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = []byte(prefix)
+		iter := txn.NewIterator(options)
+		defer iter.Close()
+
+		for iter.Seek([]byte(prefix + startKey)); iter.Valid() && (count < 0 || len(keys) < count); iter.Next() {
+			value, err := iter.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, string(iter.Item().Key()[len(prefix):]))
+			values = append(values, string(value))
+		}
+		return nil
+	})
+	return keys, values, err
+}
+
+func (db *BadgerDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	var records int = 0
+
+	JunkData = make([][]string, len(config.Patterns))
+	JunkKey = make([][]string, len(config.Patterns))
+
+	fmt.Printf("Reading Pattern Data...")
+	for session := range config.Patterns {
+		fmt.Printf("%s...", config.Patterns[session])
+		JunkKey[session], JunkData[session], err = db.scan(config.Patterns[session]+":", "", -1)
+		if err != nil {
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
+		}
+		records += len(JunkKey[session])
+		fmt.Printf("(%d records)...", len(JunkKey[session]))
+	}
+	fmt.Printf("Done! (%d records total)\n", records)
+	return JunkKey, JunkData, nil
+}
+
+func (db *BadgerDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Creating test prefixes...")
+
+	// A PREFIX EXISTS AS SOON AS A KEY IS WRITTEN, ONLY THE OLD RECORDS ARE REMOVED
+	for iter := 0; iter < nb_tables; iter++ {
+		if err = db.session.DropPrefix([]byte(prefix(SessionName, iter))); err != nil {
+			fmt.Printf("Fatal Error dropping test prefix:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+// ListTestTables returns the test prefixes of the store, registered or not,
+// jumping from one prefix to the next instead of reading every key.
+func (db *BadgerDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	err = db.session.View(func(txn *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.PrefetchValues = false
+		options.Prefix = []byte("benchmark_db_")
+		iter := txn.NewIterator(options)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); {
+			key := string(iter.Item().Key())
+			separator := strings.IndexByte(key, ':')
+			if separator < 0 {
+				iter.Next()
+				continue
+			}
+			if table := key[:separator]; table+":" != sessionPrefix {
+				tables = append(tables, table)
+			}
+			// ':' + 1 IS THE FIRST BYTE AFTER ALL THE KEYS OF THE PREFIX
+			iter.Seek([]byte(key[:separator] + ";"))
+		}
+		return nil
+	})
+	return tables, err
+}
+
+func (db *BadgerDB) DropTestTable(ctx context.Context, table string) (err error) {
+	return db.session.DropPrefix([]byte(table + ":"))
+}
+
+func (db *BadgerDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	err = db.session.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(prefix(SessionName, loop) + key))
+	})
+	if err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
+	}
+	return nil
+}
+
+func (db *BadgerDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Dropping test prefixes...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		if err = db.session.DropPrefix([]byte(prefix(SessionName, iter))); err != nil {
+			fmt.Printf("Fatal Error dropping test prefix:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+func (db *BadgerDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	return db.session.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(sessionPrefix+SessionName), []byte(info))
+	})
+}
+
+func (db *BadgerDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	names, infos, err := db.scan(sessionPrefix, "", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions = make(map[string]string)
+	for j := range names {
+		sessions[names[j]] = infos[j]
+	}
+	return sessions, nil
+}
+
+func (db *BadgerDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	return db.session.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(sessionPrefix + SessionName))
+	})
+}
+
+func (db *BadgerDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	err = db.session.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(prefix(SessionName, loop)+key), []byte(data))
+	})
+	if err != nil {
+		fmt.Printf("Write error: '%s'\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *BadgerDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	err = db.session.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(prefix(SessionName, loop) + key))
+		if err != nil {
+			return err
+		}
+		value, err := item.ValueCopy(nil)
+		data = string(value)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
+	}
+	return data, nil
+}
+
+func (db *BadgerDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	_, data, err = db.scan(prefix(SessionName, loop), startKey, count)
+	return data, err
+}
+
+func (db *BadgerDB) Close() (err error) {
+	return db.session.Close()
+}
@@ -0,0 +1,265 @@
+package bbolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+var sessionBucket = []byte("benchmark_db_sessions")
+
+type BoltDB struct {
+	session *bolt.DB
+}
+
+func New() *BoltDB {
+	var tmp BoltDB = BoltDB{}
+	return &tmp
+}
+
+func (db *BoltDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	directory := config.Embedded.Directory
+	if len(directory) == 0 {
+		directory = "benchmark_db_data"
+	}
+	if err = os.MkdirAll(directory, 0755); err != nil {
+		fmt.Printf("Failed to create the data directory: '%s'\n", err.Error())
+		return err
+	}
+
+	// DON'T WAIT FOREVER FOR THE FILE LOCK OF ANOTHER PROCESS
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	filename := filepath.Join(directory, "benchmark_db.bolt")
+	fmt.Printf("bbolt file: %s (sync: %t)\n", filename, config.Embedded.Sync)
+	db.session, err = bolt.Open(filename, 0644, &bolt.Options{Timeout: timeout, NoSync: !config.Embedded.Sync})
+	if err != nil {
+		fmt.Printf("Failed to open the database: '%s'\n", err.Error())
+		return err
+	}
+
+	fmt.Printf("Connection to database was successful!\n")
+
+	return nil
+}
+
+func bucket(SessionName string, loop int) []byte {
+	return []byte(fmt.Sprintf("benchmark_db_%s%d", SessionName, loop))
+}
+
+func (db *BoltDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	var records int = 0
+
+	JunkData = make([][]string, len(config.Patterns))
+	JunkKey = make([][]string, len(config.Patterns))
+
+	fmt.Printf("Reading Pattern Data...")
+	for session := range config.Patterns {
+		fmt.Printf("%s...", config.Patterns[session])
+		err = db.session.View(func(tx *bolt.Tx) error {
+			pattern := tx.Bucket([]byte(config.Patterns[session]))
+			if pattern == nil {
+				return bolt.ErrBucketNotFound
+			}
+			return pattern.ForEach(func(key []byte, value []byte) error {
+				JunkKey[session] = append(JunkKey[session], string(key))
+				JunkData[session] = append(JunkData[session], string(value))
+				return nil
+			})
+		})
+		if err != nil {
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
+		}
+		records += len(JunkKey[session])
+		fmt.Printf("(%d records)...", len(JunkKey[session]))
+	}
+	fmt.Printf("Done! (%d records total)\n", records)
+	return JunkKey, JunkData, nil
+}
+
+func (db *BoltDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Creating test buckets...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		err = db.session.Update(func(tx *bolt.Tx) error {
+			if err := tx.DeleteBucket(bucket(SessionName, iter)); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+			_, err := tx.CreateBucket(bucket(SessionName, iter))
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Fatal Error creating test bucket:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+// ListTestTables returns the test buckets of the file, registered or not.
+func (db *BoltDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	err = db.session.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if strings.HasPrefix(string(name), "benchmark_db_") && string(name) != string(sessionBucket) {
+				tables = append(tables, string(name))
+			}
+			return nil
+		})
+	})
+	return tables, err
+}
+
+func (db *BoltDB) DropTestTable(ctx context.Context, table string) (err error) {
+	return db.session.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(table)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	})
+}
+
+func (db *BoltDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	err = db.session.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket(SessionName, loop))
+		if data == nil {
+			return bolt.ErrBucketNotFound
+		}
+		return data.Delete([]byte(key))
+	})
+	if err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
+	}
+	return nil
+}
+
+func (db *BoltDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Dropping test buckets...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		err = db.session.Update(func(tx *bolt.Tx) error {
+			if err := tx.DeleteBucket(bucket(SessionName, iter)); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Fatal Error dropping test bucket:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+func (db *BoltDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	return db.session.Update(func(tx *bolt.Tx) error {
+		sessions, err := tx.CreateBucketIfNotExists(sessionBucket)
+		if err != nil {
+			return err
+		}
+		return sessions.Put([]byte(SessionName), []byte(info))
+	})
+}
+
+func (db *BoltDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	sessions = make(map[string]string)
+	err = db.session.View(func(tx *bolt.Tx) error {
+		registry := tx.Bucket(sessionBucket)
+		if registry == nil {
+			return nil
+		}
+		return registry.ForEach(func(name []byte, info []byte) error {
+			sessions[string(name)] = string(info)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (db *BoltDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	return db.session.Update(func(tx *bolt.Tx) error {
+		registry := tx.Bucket(sessionBucket)
+		if registry == nil {
+			return nil
+		}
+		return registry.Delete([]byte(SessionName))
+	})
+}
+
+func (db *BoltDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	err = db.session.Update(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucket(SessionName, loop))
+		if records == nil {
+			return bolt.ErrBucketNotFound
+		}
+		return records.Put([]byte(key), []byte(data))
+	})
+	if err != nil {
+		fmt.Printf("Write error: '%s'\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *BoltDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	err = db.session.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucket(SessionName, loop))
+		if records == nil {
+			return bolt.ErrBucketNotFound
+		}
+		value := records.Get([]byte(key))
+		if value == nil {
+			return ErrNotFound
+		}
+		// THE VALUE IS ONLY VALID DURING THE TRANSACTION
+		data = string(value)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
+	}
+	return data, nil
+}
+
+func (db *BoltDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	err = db.session.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucket(SessionName, loop))
+		if records == nil {
+			return bolt.ErrBucketNotFound
+		}
+		cursor := records.Cursor()
+		for key, value := cursor.Seek([]byte(startKey)); key != nil && len(data) < count; key, value = cursor.Next() {
+			data = append(data, string(value))
+		}
+		return nil
+	})
+	return data, err
+}
+
+func (db *BoltDB) Close() (err error) {
+	return db.session.Close()
+}
@@ -1,33 +1,18 @@
 package cassandra
 
 import (
+	"context"
 	"fmt"
 	"github.com/gocql/gocql"
 	"github.com/hartsp2000/benchmark_db/arguments"
 	"github.com/hartsp2000/benchmark_db/config"
-	"github.com/hartsp2000/benchmark_db/memory"
-	"github.com/hartsp2000/benchmark_db/statistics"
-	"github.com/hartsp2000/benchmark_db/timeparse"
-	"math/rand"
-	"os"
-	"sync"
+	"strings"
 	"time"
 )
 
-var mux sync.Mutex
-
 type CassandraDB struct {
-	session     *gocql.Session
-	WriteErrors int
-	ReadErrors  int
-}
-
-type Results struct {
-	ops         int64
-	duration    time.Duration
-	readErrors  int
-	writeErrors int
-	data        string
+	session  *gocql.Session
+	keyspace string
 }
 
 func New() *CassandraDB {
@@ -35,9 +20,10 @@ func New() *CassandraDB {
 	return &tmp
 }
 
-func (db *CassandraDB) Connect(config config.Config, arguments arguments.Arguments) (err error) {
+func (db *CassandraDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
 	cluster := gocql.NewCluster(config.Clusternodes...)
 	cluster.Keyspace = config.Keyspace
+	db.keyspace = config.Keyspace
 	cluster.Consistency = gocql.Quorum
 	cluster.Timeout = time.Duration(config.Timeout) * time.Second
 	cluster.Authenticator = gocql.PasswordAuthenticator{
@@ -55,20 +41,11 @@ func (db *CassandraDB) Connect(config config.Config, arguments arguments.Argumen
 
 }
 
-func (db *CassandraDB) GetReadErrors() int {
-	return db.ReadErrors
-}
-
-func (db *CassandraDB) GetWriteErrors() int {
-	return db.WriteErrors
-}
-
-func (db *CassandraDB) ReadPatternData(SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, AvailData map[int]memory.Memory, err error) {
+func (db *CassandraDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
 	var qry string
 	var records int = 0
 	var count int
 
-	AvailData = make(map[int]memory.Memory)
 	JunkData = make([][]string, len(config.Patterns))
 	JunkKey = make([][]string, len(config.Patterns))
 
@@ -76,37 +53,36 @@ func (db *CassandraDB) ReadPatternData(SessionName string, config config.Config,
 	for session := range config.Patterns {
 		fmt.Printf("%s...", config.Patterns[session])
 		qry = fmt.Sprintf("%s%s", "SELECT id, data FROM ", config.Patterns[session])
-		iter := db.session.Query(qry).PageSize(10).Iter()
+		iter := db.session.Query(qry).WithContext(ctx).PageSize(10).Iter()
 		var id string
 		var data string
 		count = 0
 		for iter.Scan(&id, &data) {
 			JunkKey[session] = append(JunkKey[session], id)
 			JunkData[session] = append(JunkData[session], data)
-			AvailData[len(AvailData)] = memory.Memory{session, count}
 			count++
 			records++
 		}
 		fmt.Printf("(%d records)...", count)
 	}
 	fmt.Printf("Done! (%d records total)\n", records)
-	return JunkKey, JunkData, AvailData, nil
+	return JunkKey, JunkData, nil
 }
 
-func (db *CassandraDB) CreateTestTables(SessionName string, nb_tables int) (err error) {
+func (db *CassandraDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
 	var qry string
 
 	fmt.Printf("Creating test tables...")
 
 	for iter := 0; iter < nb_tables; iter++ {
 		qry = fmt.Sprintf("%s%s%d", "DROP TABLE IF EXISTS benchmark_db_", SessionName, iter)
-		if err := db.session.Query(qry).Exec(); err != nil {
+		if err := db.session.Query(qry).WithContext(ctx).Exec(); err != nil {
 			fmt.Printf("Fatal Error verifying test table:\n%s\n", err)
 			return err
 		}
 
 		qry = fmt.Sprintf("%s%s%d%s", "CREATE TABLE benchmark_db_", SessionName, iter, " (id text PRIMARY KEY, data text)")
-		if err := db.session.Query(qry).Exec(); err != nil {
+		if err := db.session.Query(qry).WithContext(ctx).Exec(); err != nil {
 			fmt.Printf("Fatal Error creating test table:\n%s\n", err)
 			return err
 		}
@@ -117,382 +93,123 @@ func (db *CassandraDB) CreateTestTables(SessionName string, nb_tables int) (err
 	return nil
 }
 
-func writeTestData(SessionName string, db *CassandraDB, loop int, key string, data string) (err error) {
-	var qry string
+// ListTestTables returns the test tables of the keyspace, registered or not.
+func (db *CassandraDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	var name string
 
-	qry = fmt.Sprintf("%s%s%d%s", "UPDATE benchmark_db_", SessionName, loop, " SET data = ? WHERE id = ?")
-	if err := db.session.Query(qry, data, key).Exec(); err != nil {
-		return err
+	iter := db.session.Query("SELECT table_name FROM system_schema.tables WHERE keyspace_name = ?", db.keyspace).WithContext(ctx).Iter()
+	for iter.Scan(&name) {
+		if strings.HasPrefix(name, "benchmark_db_") && name != "benchmark_db_sessions" {
+			tables = append(tables, name)
+		}
 	}
-	return nil
+	return tables, iter.Close()
 }
 
-func WriteSequentialTestData(SessionName string, ch chan Results, db *CassandraDB, delay time.Duration, duration int, loopstart int, loopend int, iterstart int, iterend int, JunkData [][]string, JunkKey [][]string) {
-	defer close(ch)
-	var ops int64 = 0
-	errors := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		for loop := loopstart; loop < loopend; loop++ {
-			for iter := iterstart; iter < iterend; iter++ {
-				time.Sleep(delay)
-				if err := writeTestData(SessionName, db, loop, JunkKey[loop][iter], JunkData[loop][iter]); err != nil {
-					errors++
-				}
-				ops++
-			}
-		}
-	}
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.writeErrors = errors
-	ch <- *res
+func (db *CassandraDB) DropTestTable(ctx context.Context, table string) (err error) {
+	return db.session.Query(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)).WithContext(ctx).Exec()
 }
 
-func readTestData(SessionName string, db *CassandraDB, loop int, key string) (data string, err error) {
+func (db *CassandraDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
 	var qry string
 
-	qry = fmt.Sprintf("%s%s%d%s", "SELECT id, data FROM benchmark_db_", SessionName, loop, " WHERE id = ? LIMIT 1")
-	if err := db.session.Query(qry, key).Consistency(gocql.One).Scan(&key, &data); err != nil {
-		return "", err
-	}
-
-	return data, nil
+	qry = fmt.Sprintf("%s%s%d%s", "DELETE FROM benchmark_db_", SessionName, loop, " WHERE id = ?")
+	return db.session.Query(qry, key).WithContext(ctx).Exec()
 }
 
-func readOrWriteTestData(SessionName string, ch chan Results, db *CassandraDB, delay time.Duration, duration int, loop int, iter int, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, nodatacheck bool) {
-	defer close(ch)
-	var ops int64 = 0
-	readerr := 0
-	writeerr := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		readWrite := rand.Intn(2) // DETERMINE IF THIS IS READ OR WRITE
-		if readWrite == 0 {
-			rX := loop
-			rY := rand.Intn(iter)
-			time.Sleep(delay)
-			if err := writeTestData(SessionName, db, loop, JunkKey[rX][rY], JunkData[rX][rY]); err != nil {
-				writeerr++
-			}
-			mux.Lock()
-			AvailData[len(AvailData)] = memory.Memory{rX, rY}
-			mux.Unlock()
-			ops++
-			continue
-		}
-		if len(AvailData) < 1 { // LOOP AGAIN IF NO DATA WRITTEN
-			continue
-		}
-		mux.Lock()
-		dataPoint := AvailData[rand.Intn(len(AvailData))]
-		mux.Unlock()
-		randLoop := dataPoint.Loop
-		randIter := dataPoint.Iter
-		mux.Lock()
-		id := JunkKey[randLoop][randIter]
-		mux.Unlock()
-
-		data, err := readTestData(SessionName, db, randLoop, id)
-		if err != nil {
-			readerr++
-		}
-		mux.Lock()
-		if err := checkData(JunkData[randLoop][randIter], data, nodatacheck); err != nil {
-			readerr++
-		}
-		mux.Unlock()
-		ops++
-		continue
-	}
-	mux.Lock()
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.readErrors = readerr
-	res.writeErrors = writeerr
-	mux.Unlock()
-	ch <- *res
-}
+func (db *CassandraDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var qry string
 
-func ReadRandomTestData(SessionName string, ch chan Results, db *CassandraDB, delay time.Duration, duration int, loop int, iter int, JunkData [][]string, JunkKey [][]string, nodatacheck bool) {
-	defer close(ch)
-	var ops int64 = 0
-	errors := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		readIter := rand.Intn(iter)
-		time.Sleep(delay)
-		data, err := readTestData(SessionName, db, loop, JunkKey[loop][readIter])
-		if err != nil {
-			errors++
-		}
-		if err := checkData(JunkData[loop][readIter], data, nodatacheck); err != nil {
-			errors++
+	fmt.Printf("Dropping test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		qry = fmt.Sprintf("%s%s%d", "DROP TABLE IF EXISTS benchmark_db_", SessionName, iter)
+		if err := db.session.Query(qry).WithContext(ctx).Exec(); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
 		}
-		ops++
+		fmt.Printf("%d.", iter+1)
 	}
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.readErrors = errors
-	ch <- *res
-}
+	fmt.Printf("  Success.\n")
 
-func showStats(read int, write int, ops int64, duration int64, tps int64) {
-	fmt.Printf("\n\nRead Errors: %d\n", read)
-	fmt.Printf("Write Errors: %d\n", write)
-	fmt.Printf("Total Operations: %d\n", ops)
-	fmt.Printf("Time Elapsed: %d seconds\n", duration)
-	fmt.Printf("TPS Rate: %d\n\n", tps)
-	return
+	return nil
 }
 
-func checkData(ctrl string, tst string, nodatacheck bool) (err error) {
-	if nodatacheck {
-		return nil
-	}
-
-	if ctrl == tst {
-		return nil
-	}
-
-	err = fmt.Errorf("!! Data mismatch !!\nExpected: %s\nReceived:%s", ctrl, tst)
-	return err
+func (db *CassandraDB) createSessionTable(ctx context.Context) (err error) {
+	return db.session.Query("CREATE TABLE IF NOT EXISTS benchmark_db_sessions (name text PRIMARY KEY, info text)").WithContext(ctx).Exec()
 }
 
-func (db *CassandraDB) TPSTestR(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var workers map[int]chan Results
-	var TPS int64
-
-	defer wg.Done()
-
-	if i := len(arguments.Sessovrd); i == 0 {
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d: Preparing database with sample records...", loop+1)
-			for iter := 0; iter < arguments.Iterations; iter++ {
-				StartWrite := time.Now()
-				if err := writeTestData(SessionName, db, loop, JunkKey[loop][iter], JunkData[loop][iter]); err != nil {
-					fmt.Printf("\nLoop: %d, Iteration: %d --  %s\n", loop+1, iter+1, err)
-					db.WriteErrors++
-				}
-				StopWrite := time.Since(StartWrite)
-				write_stats.Add(StopWrite)
-				AvailData[len(AvailData)] = memory.Memory{loop, iter}
-			}
-			fmt.Printf("Complete.\n")
-		}
+func (db *CassandraDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	if err = db.createSessionTable(ctx); err != nil {
+		return err
 	}
+	return db.session.Query("INSERT INTO benchmark_db_sessions (name, info) VALUES (?, ?)", SessionName, info).WithContext(ctx).Exec()
+}
 
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go ReadRandomTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, arguments.Iterations, JunkData, JunkKey, arguments.NoDataCheck)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go ReadRandomTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, arguments.Iterations, JunkData, JunkKey, arguments.NoDataCheck)
-		}
-
-		fmt.Printf("Started!  Test is running...")
+func (db *CassandraDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	var name string
+	var info string
 
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
+	if err = db.createSessionTable(ctx); err != nil {
+		return nil, err
 	}
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	sessions = make(map[string]string)
+	iter := db.session.Query("SELECT name, info FROM benchmark_db_sessions").WithContext(ctx).Iter()
+	for iter.Scan(&name, &info) {
+		sessions[name] = info
+	}
+	if err = iter.Close(); err != nil {
+		return nil, err
 	}
 
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
-	return nil
+	return sessions, nil
 }
 
-func (db *CassandraDB) TPSTestW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var TPS int64
-	var workers map[int]chan Results
-
-	defer wg.Done()
-
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go WriteSequentialTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, loop+1,
-				0, arguments.Iterations, JunkData, JunkKey)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go WriteSequentialTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, channel+1,
-				0, arguments.Iterations, JunkData, JunkKey)
-		}
-
-		fmt.Printf("Started!  Test is running...")
+func (db *CassandraDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	return db.session.Query("DELETE FROM benchmark_db_sessions WHERE name = ?", SessionName).WithContext(ctx).Exec()
+}
 
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-		}
-	}
+func (db *CassandraDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	var qry string
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	qry = fmt.Sprintf("%s%s%d%s", "UPDATE benchmark_db_", SessionName, loop, " SET data = ? WHERE id = ?")
+	if err := db.session.Query(qry, data, key).WithContext(ctx).Exec(); err != nil {
+		return err
 	}
-
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
 	return nil
 }
 
-func (db *CassandraDB) TPSTestRW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var TPS int64
-	var workers map[int]chan Results
-
-	defer wg.Done()
-
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go readOrWriteTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, arguments.Iterations, JunkData, JunkKey, AvailData, arguments.NoDataCheck)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go readOrWriteTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, arguments.Iterations, JunkData, JunkKey, AvailData, arguments.NoDataCheck)
-		}
-
-		fmt.Printf("Started!  Test is running...")
-
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
-
-	}
+func (db *CassandraDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	var qry string
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	qry = fmt.Sprintf("%s%s%d%s", "SELECT id, data FROM benchmark_db_", SessionName, loop, " WHERE id = ? LIMIT 1")
+	if err := db.session.Query(qry, key).WithContext(ctx).Consistency(gocql.One).Scan(&key, &data); err != nil {
+		return "", err
 	}
 
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
-	return nil
+	return data, nil
 }
 
-func (db *CassandraDB) TestCycle(SessionName string, config config.Config, arguments arguments.Arguments, currentLoop int, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	defer wg.Done()
+// ScanData reads the rows following startKey in token order, which is the
+// only range order the random partitioner offers.
+func (db *CassandraDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	var qry string
+	var value string
 
-	fmt.Printf("Loop %d: Beginning Write Test...\n", currentLoop+1)
-	StartLoop := time.Now()
-	for iter := 0; iter < arguments.Iterations; iter++ {
-		StartWrite := time.Now()
-		if err := writeTestData(SessionName, db, currentLoop, JunkKey[currentLoop][iter], JunkData[currentLoop][iter]); err != nil {
-			fmt.Printf("Loop: %d, Iteration: %d --  %s\n", currentLoop+1, iter+1, err)
-			db.WriteErrors++
-		}
-		StopWrite := time.Since(StartWrite)
-		write_stats.Add(StopWrite)
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE token(id) >= token(?) LIMIT %d", SessionName, loop, count)
+	iter := db.session.Query(qry, startKey).WithContext(ctx).Consistency(gocql.One).Iter()
+	for iter.Scan(&value) {
+		data = append(data, value)
 	}
-	StopLoop := time.Since(StartLoop)
-
-	fmt.Printf("Loop %d: Write Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
-
-	if arguments.Mode == "w" {
-		return
+	if err = iter.Close(); err != nil {
+		return nil, err
 	}
 
-	// READ DATA AND VERIFY
-	fmt.Printf("Loop %d: Beginning Read and Verify...\n", currentLoop+1)
-	StartLoop = time.Now()
-	for iter := 0; iter < arguments.Iterations; iter++ {
-		StartRead := time.Now()
-		data, err := readTestData(SessionName, db, currentLoop, JunkKey[currentLoop][iter])
-		if err != nil {
-			fmt.Printf("Loop %d, Iteration: %d -- %s\n", currentLoop+1, iter+1, err)
-			db.ReadErrors++
-		}
-		StopRead := time.Since(StartRead)
-		read_stats.Add(StopRead)
-
-		if err := checkData(JunkData[currentLoop][iter], data, arguments.NoDataCheck); err != nil {
-			fmt.Printf("Loop %d, Iteration: %d\n%s", currentLoop+1, iter+1, err)
-			db.ReadErrors++
-		}
-	}
-	StopLoop = time.Since(StartLoop)
-	fmt.Printf("Loop %d: Read Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
+	return data, nil
+}
 
+func (db *CassandraDB) Close() (err error) {
+	db.session.Close()
 	return nil
 }
@@ -2,40 +2,27 @@ package hbase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/hartsp2000/benchmark_db/arguments"
 	"github.com/hartsp2000/benchmark_db/config"
-	"github.com/hartsp2000/benchmark_db/memory"
-	"github.com/hartsp2000/benchmark_db/statistics"
-	"github.com/hartsp2000/benchmark_db/timeparse"
 	"github.com/tsuna/gohbase"
 	"github.com/tsuna/gohbase/hrpc"
 	"io"
 	"math/rand"
-	"os"
-	"sync"
+	"strings"
 	"time"
 )
 
-var mux sync.Mutex
+var ErrNotFound = errors.New("key not found")
 
 var cFamilies = map[string]map[string]string{
 	"data": nil,
 }
 
 type HbaseDB struct {
-	session     gohbase.Client
-	sessionAdm  gohbase.AdminClient
-	WriteErrors int
-	ReadErrors  int
-}
-
-type Results struct {
-	ops         int64
-	duration    time.Duration
-	readErrors  int
-	writeErrors int
-	data        string
+	session    gohbase.Client
+	sessionAdm gohbase.AdminClient
 }
 
 func New() *HbaseDB {
@@ -43,7 +30,7 @@ func New() *HbaseDB {
 	return &tmp
 }
 
-func (db *HbaseDB) Connect(config config.Config, arguments arguments.Arguments) (err error) {
+func (db *HbaseDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
 	rand.Seed(time.Now().UTC().UnixNano())
 	zookeeper := rand.Intn(len(config.Clusternodes))
 
@@ -56,19 +43,10 @@ func (db *HbaseDB) Connect(config config.Config, arguments arguments.Arguments)
 
 }
 
-func (db *HbaseDB) GetReadErrors() int {
-	return db.ReadErrors
-}
-
-func (db *HbaseDB) GetWriteErrors() int {
-	return db.WriteErrors
-}
-
-func (db *HbaseDB) ReadPatternData(SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, AvailData map[int]memory.Memory, err error) {
+func (db *HbaseDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
 	var records int = 0
 	var count int
 
-	AvailData = make(map[int]memory.Memory)
 	JunkData = make([][]string, len(config.Patterns))
 	JunkKey = make([][]string, len(config.Patterns))
 
@@ -76,9 +54,10 @@ func (db *HbaseDB) ReadPatternData(SessionName string, config config.Config, arg
 	for session := range config.Patterns {
 		fmt.Printf("%s...", config.Patterns[session])
 
-		scanRequest, err := hrpc.NewScanStr(context.Background(), config.Patterns[session], hrpc.MaxVersions(1))
+		scanRequest, err := hrpc.NewScanStr(ctx, config.Patterns[session], hrpc.MaxVersions(1))
 		if err != nil {
-			panic(err)
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
 		}
 		result := db.session.Scan(scanRequest)
 		count = 0
@@ -87,12 +66,16 @@ func (db *HbaseDB) ReadPatternData(SessionName string, config config.Config, arg
 			if err == io.EOF {
 				break
 			}
-			JunkKey[session] = append(JunkKey[session], fmt.Sprintf("%s", rRow.Cells[0].Row))
-			JunkData[session] = append(JunkData[session], fmt.Sprintf("%s", rRow.Cells[0].Value))
-
 			if err != nil {
-				panic(err)
+				fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+				result.Close()
+				return nil, nil, err
+			}
+			if len(rRow.Cells) == 0 {
+				continue
 			}
+			JunkKey[session] = append(JunkKey[session], fmt.Sprintf("%s", rRow.Cells[0].Row))
+			JunkData[session] = append(JunkData[session], fmt.Sprintf("%s", rRow.Cells[0].Value))
 			count++
 			records++
 		}
@@ -100,10 +83,10 @@ func (db *HbaseDB) ReadPatternData(SessionName string, config config.Config, arg
 		fmt.Printf("(%d records)...", count)
 	}
 	fmt.Printf("Done! (%d records total)\n", records)
-	return JunkKey, JunkData, AvailData, nil
+	return JunkKey, JunkData, nil
 }
 
-func (db *HbaseDB) CreateTestTables(SessionName string, nb_tables int) (err error) {
+func (db *HbaseDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
 	var tableName string
 
 	fmt.Printf("Creating test tables...")
@@ -111,16 +94,17 @@ func (db *HbaseDB) CreateTestTables(SessionName string, nb_tables int) (err erro
 	for iter := 0; iter < nb_tables; iter++ {
 		tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, iter)
 
-		// TRY CREATE TABLE, IF FAILS TRY DISABLE AND DELETE TABLE, IF FAILS EXIT WITH PANIC
-		crt := hrpc.NewCreateTable(context.Background(), []byte(tableName), cFamilies)
+		// TRY CREATE TABLE, IF FAILS TRY DISABLE AND DELETE TABLE, IF FAILS RETURN THE ERROR
+		crt := hrpc.NewCreateTable(ctx, []byte(tableName), cFamilies)
 		if err := db.sessionAdm.CreateTable(crt); err != nil {
-			dit := hrpc.NewDisableTable(context.Background(), []byte(tableName))
+			dit := hrpc.NewDisableTable(ctx, []byte(tableName))
 			db.sessionAdm.DisableTable(dit)
-			det := hrpc.NewDeleteTable(context.Background(), []byte(tableName))
+			det := hrpc.NewDeleteTable(ctx, []byte(tableName))
 			db.sessionAdm.DeleteTable(det)
-			crt := hrpc.NewCreateTable(context.Background(), []byte(tableName), cFamilies)
+			crt := hrpc.NewCreateTable(ctx, []byte(tableName), cFamilies)
 			if err := db.sessionAdm.CreateTable(crt); err != nil {
-				panic(err)
+				fmt.Printf("Fatal Error creating test table:\n%s\n", err)
+				return err
 			}
 		}
 		fmt.Printf("%d.", iter+1)
@@ -130,396 +114,193 @@ func (db *HbaseDB) CreateTestTables(SessionName string, nb_tables int) (err erro
 	return nil
 }
 
-func writeTestData(SessionName string, db *HbaseDB, loop int, key string, data string) (err error) {
-	var tableName string
-
-	tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, loop)
-
-	insval := map[string]map[string][]byte{"data": map[string][]byte{"": []byte(data)}}
-	putRequest, err := hrpc.NewPutStr(context.Background(), tableName, key, insval)
+// ListTestTables returns the test tables of the cluster, registered or not.
+func (db *HbaseDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	listRequest, err := hrpc.NewListTableNames(ctx, hrpc.ListRegex("benchmark_db_.*"))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = db.session.Put(putRequest)
+	names, err := db.sessionAdm.ListTableNames(listRequest)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	return nil
+	for _, name := range names {
+		if table := string(name.GetQualifier()); table != "benchmark_db_sessions" {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
 }
 
-func WriteSequentialTestData(SessionName string, ch chan Results, db *HbaseDB, delay time.Duration, duration int, loopstart int, loopend int, iterstart int, iterend int, JunkData [][]string, JunkKey [][]string) {
-	defer close(ch)
-	var ops int64 = 0
-	errors := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		for loop := loopstart; loop < loopend; loop++ {
-			for iter := iterstart; iter < iterend; iter++ {
-				time.Sleep(delay)
-				if err := writeTestData(SessionName, db, loop, JunkKey[loop][iter], JunkData[loop][iter]); err != nil {
-					errors++
-				}
-				ops++
-			}
-		}
+func (db *HbaseDB) DropTestTable(ctx context.Context, table string) (err error) {
+	dit := hrpc.NewDisableTable(ctx, []byte(table))
+	db.sessionAdm.DisableTable(dit)
+	det := hrpc.NewDeleteTable(ctx, []byte(table))
+	if err = db.sessionAdm.DeleteTable(det); err != nil && !isTableNotFound(err) {
+		return err
 	}
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.writeErrors = errors
-	ch <- *res
+	return nil
 }
 
-func readTestData(SessionName string, db *HbaseDB, loop int, key string) (data string, err error) {
+func (db *HbaseDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
 	var tableName string
 
 	tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, loop)
 
-	getRequest, err := hrpc.NewGetStr(context.Background(), tableName, key)
-	if err != nil {
-		return "", err
-	}
-	getRsp, err := db.session.Get(getRequest)
+	delRequest, err := hrpc.NewDelStr(ctx, tableName, key, nil)
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	return fmt.Sprintf("%s", getRsp.Cells[0].Value), nil
+	_, err = db.session.Delete(delRequest)
+	return err
 }
 
-func readOrWriteTestData(SessionName string, ch chan Results, db *HbaseDB, delay time.Duration, duration int, loop int, iter int, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, nodatacheck bool) {
-	defer close(ch)
-	var ops int64 = 0
-	readerr := 0
-	writeerr := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		readWrite := rand.Intn(2) // DETERMINE IF THIS IS READ OR WRITE
-		if readWrite == 0 {
-			rX := loop
-			rY := rand.Intn(iter)
-			time.Sleep(delay)
-			if err := writeTestData(SessionName, db, loop, JunkKey[rX][rY], JunkData[rX][rY]); err != nil {
-				writeerr++
-			}
-			mux.Lock()
-			AvailData[len(AvailData)] = memory.Memory{rX, rY}
-			mux.Unlock()
-			ops++
-			continue
-		}
-		if len(AvailData) < 1 { // LOOP AGAIN IF NO DATA WRITTEN
-			continue
-		}
-		mux.Lock()
-		dataPoint := AvailData[rand.Intn(len(AvailData))]
-		mux.Unlock()
-		randLoop := dataPoint.Loop
-		randIter := dataPoint.Iter
-		mux.Lock()
-		id := JunkKey[randLoop][randIter]
-		mux.Unlock()
-
-		data, err := readTestData(SessionName, db, randLoop, id)
-		if err != nil {
-			readerr++
-		}
-		mux.Lock()
-		if err := checkData(JunkData[randLoop][randIter], data, nodatacheck); err != nil {
-			readerr++
-		}
-		mux.Unlock()
-		ops++
-		continue
-	}
-	mux.Lock()
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.readErrors = readerr
-	res.writeErrors = writeerr
-	mux.Unlock()
-	ch <- *res
-}
+func (db *HbaseDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var tableName string
 
-func ReadRandomTestData(SessionName string, ch chan Results, db *HbaseDB, delay time.Duration, duration int, loop int, iter int, JunkData [][]string, JunkKey [][]string, nodatacheck bool) {
-	defer close(ch)
-	var ops int64 = 0
-	errors := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		readIter := rand.Intn(iter)
-		time.Sleep(delay)
-		data, err := readTestData(SessionName, db, loop, JunkKey[loop][readIter])
-		if err != nil {
-			errors++
-		}
-		if err := checkData(JunkData[loop][readIter], data, nodatacheck); err != nil {
-			errors++
+	fmt.Printf("Dropping test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, iter)
+
+		// A TABLE THAT IS ALREADY DISABLED OR GONE IS NOT AN ERROR, ONLY A FAILED DELETE OF AN EXISTING TABLE IS
+		dit := hrpc.NewDisableTable(ctx, []byte(tableName))
+		db.sessionAdm.DisableTable(dit)
+		det := hrpc.NewDeleteTable(ctx, []byte(tableName))
+		if err := db.sessionAdm.DeleteTable(det); err != nil && !isTableNotFound(err) {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
 		}
-		ops++
+		fmt.Printf("%d.", iter+1)
 	}
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.readErrors = errors
-	ch <- *res
+	fmt.Printf("  Success.\n")
+
+	return nil
 }
 
-func showStats(read int, write int, ops int64, duration int64, tps int64) {
-	fmt.Printf("\n\nRead Errors: %d\n", read)
-	fmt.Printf("Write Errors: %d\n", write)
-	fmt.Printf("Total Operations: %d\n", ops)
-	fmt.Printf("Time Elapsed: %d seconds\n", duration)
-	fmt.Printf("TPS Rate: %d\n\n", tps)
-	return
+func isTableNotFound(err error) bool {
+	return strings.Contains(err.Error(), "TableNotFoundException")
 }
 
-func checkData(ctrl string, tst string, nodatacheck bool) (err error) {
-	if nodatacheck {
-		return nil
-	}
+func (db *HbaseDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	// CREATING THE REGISTRY TABLE FAILS WHEN IT ALREADY EXISTS, WHICH IS FINE
+	crt := hrpc.NewCreateTable(ctx, []byte("benchmark_db_sessions"), cFamilies)
+	db.sessionAdm.CreateTable(crt)
 
-	if ctrl == tst {
-		return nil
+	insval := map[string]map[string][]byte{"data": map[string][]byte{"": []byte(info)}}
+	putRequest, err := hrpc.NewPutStr(ctx, "benchmark_db_sessions", SessionName, insval)
+	if err != nil {
+		return err
 	}
-
-	err = fmt.Errorf("!! Data mismatch !!\nExpected: %s\nReceived:%s", ctrl, tst)
+	_, err = db.session.Put(putRequest)
 	return err
 }
 
-func (db *HbaseDB) TPSTestR(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var workers map[int]chan Results
-	var TPS int64
-
-	defer wg.Done()
-
-	if i := len(arguments.Sessovrd); i == 0 {
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d: Preparing database with sample records...", loop+1)
-			for iter := 0; iter < arguments.Iterations; iter++ {
-				StartWrite := time.Now()
-				if err := writeTestData(SessionName, db, loop, JunkKey[loop][iter], JunkData[loop][iter]); err != nil {
-					fmt.Printf("\nLoop: %d, Iteration: %d --  %s\n", loop+1, iter+1, err)
-					db.WriteErrors++
-				}
-				StopWrite := time.Since(StartWrite)
-				write_stats.Add(StopWrite)
-				AvailData[len(AvailData)] = memory.Memory{loop, iter}
-			}
-			fmt.Printf("Complete.\n")
-		}
-	}
-
-	rand.Seed(time.Now().UTC().UnixNano())
+func (db *HbaseDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	sessions = make(map[string]string)
 
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go ReadRandomTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, arguments.Iterations, JunkData, JunkKey, arguments.NoDataCheck)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.ReadErrors = db.ReadErrors + res.readErrors
+	scanRequest, err := hrpc.NewScanStr(ctx, "benchmark_db_sessions", hrpc.MaxVersions(1))
+	if err != nil {
+		return nil, err
+	}
+	result := db.session.Scan(scanRequest)
+	defer result.Close()
+	for {
+		rRow, err := result.Next()
+		if err == io.EOF {
+			break
 		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go ReadRandomTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, arguments.Iterations, JunkData, JunkKey, arguments.NoDataCheck)
+		if err != nil {
+			if isTableNotFound(err) {
+				return sessions, nil
+			}
+			return nil, err
 		}
-
-		fmt.Printf("Started!  Test is running...")
-
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.ReadErrors = db.ReadErrors + res.readErrors
+		if len(rRow.Cells) > 0 {
+			sessions[string(rRow.Cells[0].Row)] = string(rRow.Cells[0].Value)
 		}
 	}
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
-	}
-
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
-	return nil
+	return sessions, nil
 }
 
-func (db *HbaseDB) TPSTestW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var TPS int64
-	var workers map[int]chan Results
-
-	defer wg.Done()
-
-	rand.Seed(time.Now().UTC().UnixNano())
+func (db *HbaseDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	delRequest, err := hrpc.NewDelStr(ctx, "benchmark_db_sessions", SessionName, nil)
+	if err != nil {
+		return err
+	}
+	_, err = db.session.Delete(delRequest)
+	return err
+}
 
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go WriteSequentialTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, loop+1,
-				0, arguments.Iterations, JunkData, JunkKey)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go WriteSequentialTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, channel+1,
-				0, arguments.Iterations, JunkData, JunkKey)
-		}
+func (db *HbaseDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	var tableName string
 
-		fmt.Printf("Started!  Test is running...")
+	tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, loop)
 
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-		}
+	insval := map[string]map[string][]byte{"data": map[string][]byte{"": []byte(data)}}
+	putRequest, err := hrpc.NewPutStr(ctx, tableName, key, insval)
+	if err != nil {
+		return err
 	}
-
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	_, err = db.session.Put(putRequest)
+	if err != nil {
+		return err
 	}
 
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
 	return nil
 }
 
-func (db *HbaseDB) TPSTestRW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var TPS int64
-	var workers map[int]chan Results
-
-	defer wg.Done()
-
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go readOrWriteTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, arguments.Iterations, JunkData, JunkKey, AvailData, arguments.NoDataCheck)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go readOrWriteTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, arguments.Iterations, JunkData, JunkKey, AvailData, arguments.NoDataCheck)
-		}
-
-		fmt.Printf("Started!  Test is running...")
+func (db *HbaseDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	var tableName string
 
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
+	tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, loop)
 
+	getRequest, err := hrpc.NewGetStr(ctx, tableName, key)
+	if err != nil {
+		return "", err
 	}
-
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	getRsp, err := db.session.Get(getRequest)
+	if err != nil {
+		return "", err
+	}
+	// A MISSING ROW IS AN EMPTY RESULT, NOT AN ERROR
+	if len(getRsp.Cells) == 0 {
+		return "", ErrNotFound
 	}
 
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
-	return nil
+	return fmt.Sprintf("%s", getRsp.Cells[0].Value), nil
 }
 
-func (db *HbaseDB) TestCycle(SessionName string, config config.Config, arguments arguments.Arguments, currentLoop int, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	defer wg.Done()
-
-	fmt.Printf("Loop %d: Beginning Write Test...\n", currentLoop+1)
-	StartLoop := time.Now()
-	for iter := 0; iter < arguments.Iterations; iter++ {
-		StartWrite := time.Now()
-		if err := writeTestData(SessionName, db, currentLoop, JunkKey[currentLoop][iter], JunkData[currentLoop][iter]); err != nil {
-			fmt.Printf("Loop: %d, Iteration: %d --  %s\n", currentLoop+1, iter+1, err)
-			db.WriteErrors++
-		}
-		StopWrite := time.Since(StartWrite)
-		write_stats.Add(StopWrite)
-	}
-	StopLoop := time.Since(StartLoop)
+func (db *HbaseDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	var tableName string
 
-	fmt.Printf("Loop %d: Write Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
+	tableName = fmt.Sprintf("%s%s%d", "benchmark_db_", SessionName, loop)
 
-	if arguments.Mode == "w" {
-		return
+	scanRequest, err := hrpc.NewScanRangeStr(ctx, tableName, startKey, "", hrpc.MaxVersions(1), hrpc.NumberOfRows(uint32(count)))
+	if err != nil {
+		return nil, err
 	}
+	result := db.session.Scan(scanRequest)
+	defer result.Close()
 
-	// READ DATA AND VERIFY
-	fmt.Printf("Loop %d: Beginning Read and Verify...\n", currentLoop+1)
-	StartLoop = time.Now()
-	for iter := 0; iter < arguments.Iterations; iter++ {
-		StartRead := time.Now()
-		data, err := readTestData(SessionName, db, currentLoop, JunkKey[currentLoop][iter])
+	for len(data) < count {
+		rRow, err := result.Next()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			fmt.Printf("Loop %d, Iteration: %d -- %s\n", currentLoop+1, iter+1, err)
-			db.ReadErrors++
+			return nil, err
 		}
-		StopRead := time.Since(StartRead)
-		read_stats.Add(StopRead)
-
-		if err := checkData(JunkData[currentLoop][iter], data, arguments.NoDataCheck); err != nil {
-			fmt.Printf("Loop %d, Iteration: %d\n%s", currentLoop+1, iter+1, err)
-			db.ReadErrors++
+		if len(rRow.Cells) > 0 {
+			data = append(data, string(rRow.Cells[0].Value))
 		}
 	}
-	StopLoop = time.Since(StartLoop)
-	fmt.Printf("Loop %d: Read Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
 
+	return data, nil
+}
+
+func (db *HbaseDB) Close() (err error) {
+	db.session.Close()
 	return nil
 }
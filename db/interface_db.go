@@ -1,33 +1,66 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"github.com/hartsp2000/benchmark_db/arguments"
 	"github.com/hartsp2000/benchmark_db/config"
+	"github.com/hartsp2000/benchmark_db/db/badger"
+	"github.com/hartsp2000/benchmark_db/db/bbolt"
 	"github.com/hartsp2000/benchmark_db/db/cassandra"
 	"github.com/hartsp2000/benchmark_db/db/hbase"
+	"github.com/hartsp2000/benchmark_db/db/mongodb"
+	"github.com/hartsp2000/benchmark_db/db/mysql"
+	"github.com/hartsp2000/benchmark_db/db/pebble"
 	"github.com/hartsp2000/benchmark_db/db/postgres"
 	"github.com/hartsp2000/benchmark_db/db/redis"
-	"github.com/hartsp2000/benchmark_db/memory"
-	"github.com/hartsp2000/benchmark_db/statistics"
-	"sync"
+	"github.com/hartsp2000/benchmark_db/db/sqlite"
 )
 
+// Interface_DB is the set of primitive operations a database driver must
+// provide. Worker scheduling, timing, data verification and result
+// aggregation are handled by the workload engine on top of these.
 type Interface_DB interface {
-	Connect(config config.Config, arguments arguments.Arguments) (err error)
-	CreateTestTables(SessionName string, nb_tables int) (err error)
-	GetReadErrors() int
-	GetWriteErrors() int
+	Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error)
+	CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error)
 
-	ReadPatternData(SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, AvailData map[int]memory.Memory, err error)
+	ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error)
 
-	TPSTestR(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error)
+	WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error)
+	ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error)
+	DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error)
 
-	TPSTestW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error)
+	// DropTestTables removes the tables (or keys) created for a session.
+	DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error)
 
-	TPSTestRW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error)
+	// The session registry keeps one opaque record per session so that old
+	// sessions can be found and purged later.
+	SaveSession(ctx context.Context, SessionName string, info string) (err error)
+	LoadSessions(ctx context.Context) (sessions map[string]string, err error)
+	DeleteSession(ctx context.Context, SessionName string) (err error)
 
-	TestCycle(SessionName string, config config.Config, arguments arguments.Arguments, currentLoop int, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error)
+	Close() (err error)
+}
+
+// Scanner is implemented by the drivers that can read a range of keys. The
+// workload falls back to point reads for the other drivers.
+type Scanner interface {
+	ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error)
+}
+
+// TestTablePrefix starts the name of every test table, and of the key
+// prefixes that stand for tables in the key-value stores.
+const TestTablePrefix = "benchmark_db_"
+
+// TableLister is implemented by the drivers that can enumerate the test
+// tables of the database, so that the tables of sessions missing from the
+// registry (created before it existed, or whose run crashed before
+// registering them) can be found and dropped.
+type TableLister interface {
+	// ListTestTables returns the tables whose name starts with
+	// TestTablePrefix, the session registry excluded.
+	ListTestTables(ctx context.Context) (tables []string, err error)
+	DropTestTable(ctx context.Context, table string) (err error)
 }
 
 var (
@@ -43,11 +76,23 @@ func Init(config config.Config) {
 	hbase := hbase.New()
 	redis := redis.New()
 	postgres := postgres.New()
+	mysql := mysql.New()
+	mongodb := mongodb.New()
+	bbolt := bbolt.New()
+	badger := badger.New()
+	pebble := pebble.New()
+	sqlite := sqlite.New()
 
 	name2db["cassandra"] = cass
 	name2db["hbase"] = hbase
 	name2db["redis"] = redis
 	name2db["postgres"] = postgres
+	name2db["mysql"] = mysql
+	name2db["mongodb"] = mongodb
+	name2db["bbolt"] = bbolt
+	name2db["badger"] = badger
+	name2db["pebble"] = pebble
+	name2db["sqlite"] = sqlite
 }
 
 func Get(db_name string) (db Interface_DB, err error) {
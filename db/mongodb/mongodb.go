@@ -0,0 +1,262 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"strconv"
+)
+
+// record is the document of a key, the key being its _id.
+type record struct {
+	ID   string `bson:"_id"`
+	Data string `bson:"data"`
+}
+
+// sessionRecord is the document of a session in the registry.
+type sessionRecord struct {
+	Name string `bson:"_id"`
+	Info string `bson:"info"`
+}
+
+type MongoDB struct {
+	client   *mongo.Client
+	database *mongo.Database
+}
+
+func New() *MongoDB {
+	var tmp MongoDB = MongoDB{}
+	return &tmp
+}
+
+func (db *MongoDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	fmt.Printf("mongodb options: %s\n", config.Redacted().MongoDB.URI)
+	clientOptions := options.Client().ApplyURI(config.MongoDB.URI)
+
+	if len(config.MongoDB.WriteConcern) > 0 || config.MongoDB.Journal {
+		var concern []writeconcern.Option
+		if config.MongoDB.WriteConcern == "majority" {
+			concern = append(concern, writeconcern.WMajority())
+		} else if nodes, err := strconv.Atoi(config.MongoDB.WriteConcern); err == nil {
+			concern = append(concern, writeconcern.W(nodes))
+		} else if len(config.MongoDB.WriteConcern) > 0 {
+			concern = append(concern, writeconcern.WTagSet(config.MongoDB.WriteConcern))
+		}
+		if config.MongoDB.Journal {
+			concern = append(concern, writeconcern.J(true))
+		}
+		clientOptions.SetWriteConcern(writeconcern.New(concern...))
+	}
+
+	if len(config.MongoDB.ReadPreference) > 0 {
+		mode, err := readpref.ModeFromString(config.MongoDB.ReadPreference)
+		if err != nil {
+			fmt.Printf("Invalid read preference: '%s'\n", err.Error())
+			return err
+		}
+		preference, err := readpref.New(mode)
+		if err != nil {
+			fmt.Printf("Invalid read preference: '%s'\n", err.Error())
+			return err
+		}
+		clientOptions.SetReadPreference(preference)
+	}
+
+	if err = clientOptions.Validate(); err != nil {
+		fmt.Printf("Failed to create session: '%s'\n", err.Error())
+		return err
+	}
+	if db.client, err = mongo.Connect(ctx, clientOptions); err != nil {
+		fmt.Printf("Failed to create session: '%s'\n", err.Error())
+		return err
+	}
+	if err = db.client.Ping(ctx, nil); err != nil {
+		fmt.Printf("Failed to connect: '%s'\n", err.Error())
+		db.client.Disconnect(ctx)
+		return err
+	}
+
+	name := config.MongoDB.Database
+	if len(name) == 0 {
+		name = "benchmark_db"
+	}
+	db.database = db.client.Database(name)
+
+	fmt.Printf("Connection to database was successful!\n")
+
+	return nil
+}
+
+func (db *MongoDB) collection(SessionName string, loop int) *mongo.Collection {
+	return db.database.Collection(fmt.Sprintf("benchmark_db_%s%d", SessionName, loop))
+}
+
+func (db *MongoDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	var records int = 0
+
+	JunkData = make([][]string, len(config.Patterns))
+	JunkKey = make([][]string, len(config.Patterns))
+
+	fmt.Printf("Reading Pattern Data...")
+	for session := range config.Patterns {
+		fmt.Printf("%s...", config.Patterns[session])
+		cursor, err := db.database.Collection(config.Patterns[session]).Find(ctx, bson.D{})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var patterns []record
+		if err = cursor.All(ctx, &patterns); err != nil {
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
+		}
+		for _, pattern := range patterns {
+			JunkKey[session] = append(JunkKey[session], pattern.ID)
+			JunkData[session] = append(JunkData[session], pattern.Data)
+		}
+		records += len(patterns)
+		fmt.Printf("(%d records)...", len(patterns))
+	}
+	fmt.Printf("Done! (%d records total)\n", records)
+	return JunkKey, JunkData, nil
+}
+
+func (db *MongoDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Creating test collections...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		if err = db.collection(SessionName, iter).Drop(ctx); err != nil {
+			fmt.Printf("Fatal Error dropping test collection:\n%s\n", err)
+			return err
+		}
+		if err = db.database.CreateCollection(ctx, db.collection(SessionName, iter).Name()); err != nil {
+			fmt.Printf("Fatal Error creating test collection:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+// ListTestTables returns the test collections of the database, registered or
+// not.
+func (db *MongoDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	names, err := db.database.ListCollectionNames(ctx, bson.M{"name": bson.M{"$regex": "^benchmark_db_"}})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if name != "benchmark_db_sessions" {
+			tables = append(tables, name)
+		}
+	}
+	return tables, nil
+}
+
+func (db *MongoDB) DropTestTable(ctx context.Context, table string) (err error) {
+	return db.database.Collection(table).Drop(ctx)
+}
+
+func (db *MongoDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	if _, err = db.collection(SessionName, loop).DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
+	}
+	return nil
+}
+
+func (db *MongoDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Dropping test collections...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		if err = db.collection(SessionName, iter).Drop(ctx); err != nil {
+			fmt.Printf("Fatal Error dropping test collection:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+func (db *MongoDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	_, err = db.database.Collection("benchmark_db_sessions").ReplaceOne(ctx, bson.M{"_id": SessionName},
+		sessionRecord{Name: SessionName, Info: info}, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (db *MongoDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	cursor, err := db.database.Collection("benchmark_db_sessions").Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []sessionRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	sessions = make(map[string]string)
+	for _, session := range records {
+		sessions[session.Name] = session.Info
+	}
+
+	return sessions, nil
+}
+
+func (db *MongoDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	_, err = db.database.Collection("benchmark_db_sessions").DeleteOne(ctx, bson.M{"_id": SessionName})
+	return err
+}
+
+func (db *MongoDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	_, err = db.collection(SessionName, loop).ReplaceOne(ctx, bson.M{"_id": key}, record{ID: key, Data: data},
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		fmt.Printf("Write error: '%s'\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *MongoDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	var found record
+
+	if err = db.collection(SessionName, loop).FindOne(ctx, bson.M{"_id": key}).Decode(&found); err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
+	}
+	return found.Data, nil
+}
+
+func (db *MongoDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	cursor, err := db.collection(SessionName, loop).Find(ctx, bson.M{"_id": bson.M{"$gte": startKey}},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(count)))
+	if err != nil {
+		return nil, err
+	}
+
+	var found []record
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, value := range found {
+		data = append(data, value.Data)
+	}
+
+	return data, nil
+}
+
+func (db *MongoDB) Close() (err error) {
+	return db.client.Disconnect(context.Background())
+}
@@ -0,0 +1,281 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"strings"
+)
+
+// maxKeyBytes is the longest primary key InnoDB indexes (with the default
+// DYNAMIC row format and 16KB pages).
+const maxKeyBytes = 3072
+
+type MySQLDB struct {
+	session  *sql.DB
+	options  string
+	keyBytes int
+}
+
+func New() *MySQLDB {
+	var tmp MySQLDB = MySQLDB{}
+	return &tmp
+}
+
+func (db *MySQLDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	fmt.Printf("mysql options: %s\n", config.Redacted().MySQL.DSN)
+	db.session, err = sql.Open("mysql", config.MySQL.DSN)
+	if err != nil {
+		fmt.Printf("Failed to create session: '%s'\n", err.Error())
+		return err
+	}
+
+	if err = db.session.PingContext(ctx); err != nil {
+		fmt.Printf("Failed to connect: '%s'\n", err.Error())
+		db.session.Close()
+		return err
+	}
+
+	// TABLE OPTIONS OF THE TEST AND SESSION TABLES
+	db.options = ""
+	if len(config.MySQL.Engine) > 0 {
+		db.options += " ENGINE=" + config.MySQL.Engine
+	}
+	if len(config.MySQL.Charset) > 0 {
+		db.options += " DEFAULT CHARSET=" + config.MySQL.Charset
+	}
+
+	// THE KEYS ARE BINARY: LONGER THAN A VARCHAR INDEX ALLOWS AND CASE SENSITIVE WHATEVER THE COLLATION
+	db.keyBytes = arguments.KeyBS
+	if db.keyBytes < 255 {
+		db.keyBytes = 255
+	}
+
+	fmt.Printf("Connection to database was successful!\n")
+
+	return nil
+}
+
+func (db *MySQLDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	var records int = 0
+
+	JunkData = make([][]string, len(config.Patterns))
+	JunkKey = make([][]string, len(config.Patterns))
+
+	fmt.Printf("Reading Pattern Data...")
+	for session := range config.Patterns {
+		fmt.Printf("%s...", config.Patterns[session])
+		JunkKey[session], JunkData[session], err = db.readPattern(ctx, config.Patterns[session])
+		if err != nil {
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
+		}
+		records += len(JunkKey[session])
+		fmt.Printf("(%d records)...", len(JunkKey[session]))
+	}
+	fmt.Printf("Done! (%d records total)\n", records)
+	return JunkKey, JunkData, nil
+}
+
+func (db *MySQLDB) readPattern(ctx context.Context, table string) (keys []string, values []string, err error) {
+	var id string
+	var data string
+
+	rows, err := db.session.QueryContext(ctx, fmt.Sprintf("SELECT id, data FROM %s", table))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&id, &data); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, id)
+		values = append(values, data)
+	}
+	return keys, values, rows.Err()
+}
+
+func (db *MySQLDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var qry string
+
+	if db.keyBytes > maxKeyBytes {
+		err = fmt.Errorf("-kbs %d is above the %d bytes InnoDB allows in a primary key", db.keyBytes, maxKeyBytes)
+		fmt.Printf("Fatal Error creating test tables: %s\n", err)
+		return err
+	}
+
+	fmt.Printf("Creating test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		qry = fmt.Sprintf("DROP TABLE IF EXISTS benchmark_db_%s%d", SessionName, iter)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
+		}
+
+		qry = fmt.Sprintf("CREATE TABLE benchmark_db_%s%d (id VARBINARY(%d) PRIMARY KEY, data LONGTEXT)%s", SessionName, iter,
+			db.keyBytes, db.options)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error creating test table:\n%s\n", err)
+			return err
+		}
+
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+// ListTestTables returns the test tables of the database, registered or not.
+func (db *MySQLDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	var name string
+
+	rows, err := db.session.QueryContext(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(name, "benchmark_db_") && name != "benchmark_db_sessions" {
+			tables = append(tables, name)
+		}
+	}
+	return tables, rows.Err()
+}
+
+func (db *MySQLDB) DropTestTable(ctx context.Context, table string) (err error) {
+	_, err = db.session.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+	return err
+}
+
+func (db *MySQLDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	var qry string
+
+	qry = fmt.Sprintf("DELETE FROM benchmark_db_%s%d WHERE id = ?", SessionName, loop)
+	if _, err = db.session.ExecContext(ctx, qry, key); err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
+	}
+	return nil
+}
+
+func (db *MySQLDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var qry string
+
+	fmt.Printf("Dropping test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		qry = fmt.Sprintf("DROP TABLE IF EXISTS benchmark_db_%s%d", SessionName, iter)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+func (db *MySQLDB) createSessionTable(ctx context.Context) (err error) {
+	_, err = db.session.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS benchmark_db_sessions (name VARBINARY(255) PRIMARY KEY, info LONGTEXT)"+db.options)
+	return err
+}
+
+func (db *MySQLDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	if err = db.createSessionTable(ctx); err != nil {
+		return err
+	}
+	_, err = db.session.ExecContext(ctx, "INSERT INTO benchmark_db_sessions(name, info) VALUES(?, ?) ON DUPLICATE KEY UPDATE info = VALUES(info)", SessionName, info)
+	return err
+}
+
+func (db *MySQLDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	var name string
+	var info string
+
+	if err = db.createSessionTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.session.QueryContext(ctx, "SELECT name, info FROM benchmark_db_sessions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions = make(map[string]string)
+	for rows.Next() {
+		if err = rows.Scan(&name, &info); err != nil {
+			return nil, err
+		}
+		sessions[name] = info
+	}
+
+	return sessions, rows.Err()
+}
+
+func (db *MySQLDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	_, err = db.session.ExecContext(ctx, "DELETE FROM benchmark_db_sessions WHERE name = ?", SessionName)
+	return err
+}
+
+func (db *MySQLDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	var qry string
+
+	// VALUES() IS DEPRECATED ON MYSQL 8.0.20+ BUT IS THE ONLY SYNTAX MARIADB ALSO ACCEPTS
+	qry = fmt.Sprintf("INSERT INTO benchmark_db_%s%d(id, data) VALUES(?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data)", SessionName, loop)
+	if _, err = db.session.ExecContext(ctx, qry, key, data); err != nil {
+		fmt.Printf("Write error: '%s'\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *MySQLDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	var qry string
+
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id = ? LIMIT 1", SessionName, loop)
+	err = db.session.QueryRowContext(ctx, qry, key).Scan(&data)
+	if err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
+	}
+	return data, nil
+}
+
+func (db *MySQLDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	var qry string
+	var value string
+
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id >= ? ORDER BY id LIMIT ?", SessionName, loop)
+	rows, err := db.session.QueryContext(ctx, qry, startKey, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		data = append(data, value)
+	}
+
+	return data, rows.Err()
+}
+
+func (db *MySQLDB) Close() (err error) {
+	return db.session.Close()
+}
@@ -0,0 +1,203 @@
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/pebble"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"path/filepath"
+	"strings"
+)
+
+// The records of a loop are the keys under its prefix, the sessions those
+// under the prefix of the registry.
+const sessionPrefix = "benchmark_db_sessions:"
+
+type PebbleDB struct {
+	session *pebble.DB
+	write   *pebble.WriteOptions
+}
+
+func New() *PebbleDB {
+	var tmp PebbleDB = PebbleDB{}
+	return &tmp
+}
+
+func (db *PebbleDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	directory := config.Embedded.Directory
+	if len(directory) == 0 {
+		directory = "benchmark_db_data"
+	}
+	directory = filepath.Join(directory, "pebble")
+
+	fmt.Printf("pebble directory: %s (sync: %t)\n", directory, config.Embedded.Sync)
+	if db.session, err = pebble.Open(directory, &pebble.Options{}); err != nil {
+		fmt.Printf("Failed to open the database: '%s'\n", err.Error())
+		return err
+	}
+	db.write = pebble.NoSync
+	if config.Embedded.Sync {
+		db.write = pebble.Sync
+	}
+
+	fmt.Printf("Connection to database was successful!\n")
+
+	return nil
+}
+
+func prefix(SessionName string, loop int) string {
+	return fmt.Sprintf("benchmark_db_%s%d:", SessionName, loop)
+}
+
+// end is the first key after all the keys under the prefix.
+func end(prefix string) []byte {
+	upper := []byte(prefix)
+	upper[len(upper)-1]++
+	return upper
+}
+
+// scan returns the keys, without the prefix, and the values of up to count
+// records under the prefix from the start key on, all of them when count
+// is negative.
+func (db *PebbleDB) scan(prefix string, startKey string, count int) (keys []string, values []string, err error) {
+	iter := db.session.NewIter(&pebble.IterOptions{LowerBound: []byte(prefix + startKey), UpperBound: end(prefix)})
+	for iter.First(); iter.Valid() && (count < 0 || len(keys) < count); iter.Next() {
+		keys = append(keys, string(iter.Key()[len(prefix):]))
+		values = append(values, string(iter.Value()))
+	}
+	return keys, values, iter.Close()
+}
+
+func (db *PebbleDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	var records int = 0
+
+	JunkData = make([][]string, len(config.Patterns))
+	JunkKey = make([][]string, len(config.Patterns))
+
+	fmt.Printf("Reading Pattern Data...")
+	for session := range config.Patterns {
+		fmt.Printf("%s...", config.Patterns[session])
+		JunkKey[session], JunkData[session], err = db.scan(config.Patterns[session]+":", "", -1)
+		if err != nil {
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
+		}
+		records += len(JunkKey[session])
+		fmt.Printf("(%d records)...", len(JunkKey[session]))
+	}
+	fmt.Printf("Done! (%d records total)\n", records)
+	return JunkKey, JunkData, nil
+}
+
+func (db *PebbleDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Creating test prefixes...")
+
+	// A PREFIX EXISTS AS SOON AS A KEY IS WRITTEN, ONLY THE OLD RECORDS ARE REMOVED
+	for iter := 0; iter < nb_tables; iter++ {
+		if err = db.session.DeleteRange([]byte(prefix(SessionName, iter)), end(prefix(SessionName, iter)), db.write); err != nil {
+			fmt.Printf("Fatal Error dropping test prefix:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+// ListTestTables returns the test prefixes of the store, registered or not,
+// jumping from one prefix to the next instead of reading every key.
+func (db *PebbleDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	iter := db.session.NewIter(&pebble.IterOptions{LowerBound: []byte("benchmark_db_"), UpperBound: end("benchmark_db_")})
+	for valid := iter.First(); valid; {
+		key := string(iter.Key())
+		separator := strings.IndexByte(key, ':')
+		if separator < 0 {
+			valid = iter.Next()
+			continue
+		}
+		if table := key[:separator]; table+":" != sessionPrefix {
+			tables = append(tables, table)
+		}
+		valid = iter.SeekGE(end(key[:separator+1]))
+	}
+	return tables, iter.Close()
+}
+
+func (db *PebbleDB) DropTestTable(ctx context.Context, table string) (err error) {
+	return db.session.DeleteRange([]byte(table+":"), end(table+":"), db.write)
+}
+
+func (db *PebbleDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	if err = db.session.Delete([]byte(prefix(SessionName, loop)+key), db.write); err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
+	}
+	return nil
+}
+
+func (db *PebbleDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	fmt.Printf("Dropping test prefixes...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		if err = db.session.DeleteRange([]byte(prefix(SessionName, iter)), end(prefix(SessionName, iter)), db.write); err != nil {
+			fmt.Printf("Fatal Error dropping test prefix:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+func (db *PebbleDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	return db.session.Set([]byte(sessionPrefix+SessionName), []byte(info), db.write)
+}
+
+func (db *PebbleDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	names, infos, err := db.scan(sessionPrefix, "", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions = make(map[string]string)
+	for j := range names {
+		sessions[names[j]] = infos[j]
+	}
+	return sessions, nil
+}
+
+func (db *PebbleDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	return db.session.Delete([]byte(sessionPrefix+SessionName), db.write)
+}
+
+func (db *PebbleDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	if err = db.session.Set([]byte(prefix(SessionName, loop)+key), []byte(data), db.write); err != nil {
+		fmt.Printf("Write error: '%s'\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *PebbleDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	value, closer, err := db.session.Get([]byte(prefix(SessionName, loop) + key))
+	if err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
+	}
+	// THE VALUE IS ONLY VALID UNTIL THE CLOSER IS CLOSED
+	data = string(value)
+	return data, closer.Close()
+}
+
+func (db *PebbleDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	_, data, err = db.scan(prefix(SessionName, loop), startKey, count)
+	return data, err
+}
+
+func (db *PebbleDB) Close() (err error) {
+	return db.session.Close()
+}
@@ -1,34 +1,17 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/hartsp2000/benchmark_db/arguments"
 	"github.com/hartsp2000/benchmark_db/config"
-	"github.com/hartsp2000/benchmark_db/memory"
-	"github.com/hartsp2000/benchmark_db/statistics"
-	"github.com/hartsp2000/benchmark_db/timeparse"
 	_ "github.com/lib/pq"
-	"math/rand"
-	"os"
-	"sync"
-	"time"
+	"strings"
 )
 
-var mux sync.Mutex
-
 type PostgresDB struct {
-	session     *sql.DB
-	WriteErrors int
-	ReadErrors  int
-}
-
-type Results struct {
-	ops         int64
-	duration    time.Duration
-	readErrors  int
-	writeErrors int
-	data        string
+	session *sql.DB
 }
 
 func New() *PostgresDB {
@@ -36,8 +19,8 @@ func New() *PostgresDB {
 	return &tmp
 }
 
-func (db *PostgresDB) Connect(config config.Config, arguments arguments.Arguments) (err error) {
-	fmt.Printf("postgres options: %s", config.PSQL)
+func (db *PostgresDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	fmt.Printf("postgres options: %s\n", config.Redacted().PSQL)
 	db.session, err = sql.Open("postgres", config.PSQL)
 
 	if err != nil {
@@ -45,26 +28,24 @@ func (db *PostgresDB) Connect(config config.Config, arguments arguments.Argument
 		return err
 	}
 
+	// sql.Open ONLY CHECKS THE DSN, THE PING OPENS THE FIRST CONNECTION
+	if err = db.session.PingContext(ctx); err != nil {
+		fmt.Printf("Failed to connect: '%s'\n", err.Error())
+		db.session.Close()
+		return err
+	}
+
 	fmt.Printf("Connection to database was successful!\n")
 
 	return nil
 
 }
 
-func (db *PostgresDB) GetReadErrors() int {
-	return db.ReadErrors
-}
-
-func (db *PostgresDB) GetWriteErrors() int {
-	return db.WriteErrors
-}
-
-func (db *PostgresDB) ReadPatternData(SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, AvailData map[int]memory.Memory, err error) {
+func (db *PostgresDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
 	var qry string
 	var records int = 0
 	var count int
 
-	AvailData = make(map[int]memory.Memory)
 	JunkData = make([][]string, len(config.Patterns))
 	JunkKey = make([][]string, len(config.Patterns))
 
@@ -72,9 +53,9 @@ func (db *PostgresDB) ReadPatternData(SessionName string, config config.Config,
 	for session := range config.Patterns {
 		fmt.Printf("%s...", config.Patterns[session])
 		qry = fmt.Sprintf("%s%s", "SELECT id, data FROM ", config.Patterns[session])
-		iter, err := db.session.Query(qry)
+		iter, err := db.session.QueryContext(ctx, qry)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, err
 		}
 
 		defer iter.Close()
@@ -87,36 +68,35 @@ func (db *PostgresDB) ReadPatternData(SessionName string, config config.Config,
 			err = iter.Scan(&id, &data)
 			if err != nil {
 				fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
-				return nil, nil, nil, err
+				return nil, nil, err
 			}
 			JunkKey[session] = append(JunkKey[session], id)
 			JunkData[session] = append(JunkData[session], data)
-			AvailData[len(AvailData)] = memory.Memory{session, count}
 			count++
 			records++
 		}
 		fmt.Printf("(%d records)...", count)
 	}
 	fmt.Printf("Done! (%d records total)\n", records)
-	return JunkKey, JunkData, AvailData, nil
+	return JunkKey, JunkData, nil
 }
 
-func (db *PostgresDB) CreateTestTables(SessionName string, nb_tables int) (err error) {
+func (db *PostgresDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
 	var qry string
 
 	fmt.Printf("Creating test tables...")
 
 	for iter := 0; iter < nb_tables; iter++ {
 		qry = fmt.Sprintf("%s%s%d", "DROP TABLE IF EXISTS benchmark_db_", SessionName, iter)
-		row, qerr := db.session.Query(qry)
-		if qerr == nil {
-			row.Close()
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
 		}
 
 		qry = fmt.Sprintf("%s%s%d%s", "CREATE TABLE benchmark_db_", SessionName, iter, " (id text PRIMARY KEY, data text)")
-		row, qerr = db.session.Query(qry)
-		if qerr == nil {
-			row.Close()
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error creating test table:\n%s\n", err)
+			return err
 		}
 
 		fmt.Printf("%d.", iter+1)
@@ -126,388 +106,152 @@ func (db *PostgresDB) CreateTestTables(SessionName string, nb_tables int) (err e
 	return nil
 }
 
-func writeTestData(SessionName string, db *PostgresDB, loop int, key string, data string) (err error) {
-	var qry string
+// ListTestTables returns the test tables of the database, registered or not.
+func (db *PostgresDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	var name string
 
-	qry = fmt.Sprintf("INSERT INTO benchmark_db_%s%d(data, id) VALUES($1, $2) ON CONFLICT (id) DO UPDATE SET data = $1", SessionName, loop)
-	row, qerr := db.session.Query(qry, data, key)
-	if qerr != nil {
-		fmt.Printf("Write error: '%s'\n", qerr)
-		return qerr
-	} else {
-		row.Close()
+	rows, err := db.session.QueryContext(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = current_schema()")
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
-}
-
-func WriteSequentialTestData(SessionName string, ch chan Results, db *PostgresDB, delay time.Duration, duration int, loopstart int, loopend int, iterstart int, iterend int, JunkData [][]string, JunkKey [][]string) {
-	defer close(ch)
-	var ops int64 = 0
-	errors := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		for loop := loopstart; loop < loopend; loop++ {
-			for iter := iterstart; iter < iterend; iter++ {
-				time.Sleep(delay)
-				if err := writeTestData(SessionName, db, loop, JunkKey[loop][iter], JunkData[loop][iter]); err != nil {
-					errors++
-				}
-				ops++
-			}
+	for rows.Next() {
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(name, "benchmark_db_") && name != "benchmark_db_sessions" {
+			tables = append(tables, name)
 		}
 	}
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.writeErrors = errors
-	ch <- *res
+	return tables, rows.Err()
 }
 
-func readTestData(SessionName string, db *PostgresDB, loop int, key string) (data string, err error) {
+func (db *PostgresDB) DropTestTable(ctx context.Context, table string) (err error) {
+	_, err = db.session.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+	return err
+}
+
+func (db *PostgresDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
 	var qry string
 
-	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id = $1 LIMIT 1", SessionName, loop)
-	err = db.session.QueryRow(qry, key).Scan(&data)
-	if err != nil {
-		fmt.Printf("Read error: '%s'\n", err)
-		return "", err
+	qry = fmt.Sprintf("DELETE FROM benchmark_db_%s%d WHERE id = $1", SessionName, loop)
+	if _, err = db.session.ExecContext(ctx, qry, key); err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
 	}
-	return data, nil
+	return nil
 }
 
-func readOrWriteTestData(SessionName string, ch chan Results, db *PostgresDB, delay time.Duration, duration int, loop int, iter int, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, nodatacheck bool) {
-	defer close(ch)
-	var ops int64 = 0
-	readerr := 0
-	writeerr := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		readWrite := rand.Intn(2) // DETERMINE IF THIS IS READ OR WRITE
-		if readWrite == 0 {
-			rX := loop
-			rY := rand.Intn(iter)
-			time.Sleep(delay)
-			if err := writeTestData(SessionName, db, loop, JunkKey[rX][rY], JunkData[rX][rY]); err != nil {
-				writeerr++
-			}
-			mux.Lock()
-			AvailData[len(AvailData)] = memory.Memory{rX, rY}
-			mux.Unlock()
-			ops++
-			continue
-		}
-		if len(AvailData) < 1 { // LOOP AGAIN IF NO DATA WRITTEN
-			continue
-		}
-		mux.Lock()
-		dataPoint := AvailData[rand.Intn(len(AvailData))]
-		mux.Unlock()
-		randLoop := dataPoint.Loop
-		randIter := dataPoint.Iter
-		mux.Lock()
-		id := JunkKey[randLoop][randIter]
-		mux.Unlock()
-
-		data, err := readTestData(SessionName, db, randLoop, id)
-		if err != nil {
-			readerr++
-		}
-		mux.Lock()
-		if err := checkData(JunkData[randLoop][randIter], data, nodatacheck); err != nil {
-			readerr++
-		}
-		mux.Unlock()
-		ops++
-		continue
-	}
-	mux.Lock()
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.readErrors = readerr
-	res.writeErrors = writeerr
-	mux.Unlock()
-	ch <- *res
-}
+func (db *PostgresDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var qry string
 
-func ReadRandomTestData(SessionName string, ch chan Results, db *PostgresDB, delay time.Duration, duration int, loop int, iter int, JunkData [][]string, JunkKey [][]string, nodatacheck bool) {
-	defer close(ch)
-	var ops int64 = 0
-	errors := 0
-	startTest := time.Now()
-	start := time.Now().Unix()
-	stop := start + (int64(duration) * 60)
-	res := new(Results)
-
-	for time.Now().Unix() < stop {
-		readIter := rand.Intn(iter)
-		time.Sleep(delay)
-		data, err := readTestData(SessionName, db, loop, JunkKey[loop][readIter])
-		if err != nil {
-			errors++
-		}
-		if err := checkData(JunkData[loop][readIter], data, nodatacheck); err != nil {
-			errors++
+	fmt.Printf("Dropping test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		qry = fmt.Sprintf("%s%s%d", "DROP TABLE IF EXISTS benchmark_db_", SessionName, iter)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
 		}
-		ops++
+		fmt.Printf("%d.", iter+1)
 	}
-	res.ops = ops
-	res.duration = time.Since(startTest)
-	res.readErrors = errors
-	ch <- *res
-}
+	fmt.Printf("  Success.\n")
 
-func showStats(read int, write int, ops int64, duration int64, tps int64) {
-	fmt.Printf("\n\nRead Errors: %d\n", read)
-	fmt.Printf("Write Errors: %d\n", write)
-	fmt.Printf("Total Operations: %d\n", ops)
-	fmt.Printf("Time Elapsed: %d seconds\n", duration)
-	fmt.Printf("TPS Rate: %d\n\n", tps)
-	return
+	return nil
 }
 
-func checkData(ctrl string, tst string, nodatacheck bool) (err error) {
-	if nodatacheck {
-		return nil
-	}
-
-	if ctrl == tst {
-		return nil
-	}
-
-	err = fmt.Errorf("!! Data mismatch !!\nExpected: %s\nReceived:%s", ctrl, tst)
+func (db *PostgresDB) createSessionTable(ctx context.Context) (err error) {
+	_, err = db.session.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS benchmark_db_sessions (name text PRIMARY KEY, info text)")
 	return err
 }
 
-func (db *PostgresDB) TPSTestR(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var workers map[int]chan Results
-	var TPS int64
-
-	defer wg.Done()
-
-	if i := len(arguments.Sessovrd); i == 0 {
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d: Preparing database with sample records...", loop+1)
-			for iter := 0; iter < arguments.Iterations; iter++ {
-				StartWrite := time.Now()
-				if err := writeTestData(SessionName, db, loop, JunkKey[loop][iter], JunkData[loop][iter]); err != nil {
-					fmt.Printf("\nLoop: %d, Iteration: %d --  %s\n", loop+1, iter+1, err)
-					db.WriteErrors++
-				}
-				StopWrite := time.Since(StartWrite)
-				write_stats.Add(StopWrite)
-				AvailData[len(AvailData)] = memory.Memory{loop, iter}
-			}
-			fmt.Printf("Complete.\n")
-		}
+func (db *PostgresDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	if err = db.createSessionTable(ctx); err != nil {
+		return err
 	}
+	_, err = db.session.ExecContext(ctx, "INSERT INTO benchmark_db_sessions(name, info) VALUES($1, $2) ON CONFLICT (name) DO UPDATE SET info = $2", SessionName, info)
+	return err
+}
 
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go ReadRandomTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, arguments.Iterations, JunkData, JunkKey, arguments.NoDataCheck)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go ReadRandomTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, arguments.Iterations, JunkData, JunkKey, arguments.NoDataCheck)
-		}
-
-		fmt.Printf("Started!  Test is running...")
+func (db *PostgresDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	var name string
+	var info string
 
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
+	if err = db.createSessionTable(ctx); err != nil {
+		return nil, err
 	}
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	rows, err := db.session.QueryContext(ctx, "SELECT name, info FROM benchmark_db_sessions")
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
-	return nil
-}
-
-func (db *PostgresDB) TPSTestW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var TPS int64
-	var workers map[int]chan Results
-
-	defer wg.Done()
-
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go WriteSequentialTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, loop+1,
-				0, arguments.Iterations, JunkData, JunkKey)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go WriteSequentialTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, channel+1,
-				0, arguments.Iterations, JunkData, JunkKey)
-		}
-
-		fmt.Printf("Started!  Test is running...")
-
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
+	sessions = make(map[string]string)
+	for rows.Next() {
+		if err = rows.Scan(&name, &info); err != nil {
+			return nil, err
 		}
+		sessions[name] = info
 	}
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
-	}
-
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
-	return nil
+	return sessions, rows.Err()
 }
 
-func (db *PostgresDB) TPSTestRW(SessionName string, config config.Config, arguments arguments.Arguments, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, AvailData map[int]memory.Memory, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	var ops int64
-	var elapsedTime int64
-	var TPS int64
-	var workers map[int]chan Results
-
-	defer wg.Done()
-
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	ops = 0
-	StartTest := time.Now().Unix()
-	intervalDuration := timeparse.ParseDuration(arguments.Interval)
-	workers = make(map[int]chan Results)
-
-	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
-	if arguments.TpsWorkers == 1 {
-		// SINGLE WORKER
-		for loop := 0; loop < arguments.Loops; loop++ {
-			fmt.Printf("Loop %d...", loop+1)
-			workers[loop] = make(chan Results, 1)
-			go readOrWriteTestData(SessionName, workers[loop], db, intervalDuration, arguments.Duration, loop, arguments.Iterations, JunkData, JunkKey, AvailData, arguments.NoDataCheck)
-			res := <-workers[loop]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
-	} else {
-		// FOR MULTIPLE WORKERS
-		for channel := 0; channel < arguments.TpsWorkers; channel++ {
-			workers[channel] = make(chan Results, arguments.TpsWorkers)
-			go readOrWriteTestData(SessionName, workers[channel], db, intervalDuration, arguments.Duration, channel, arguments.Iterations, JunkData, JunkKey, AvailData, arguments.NoDataCheck)
-		}
-
-		fmt.Printf("Started!  Test is running...")
-
-		for results := range workers {
-			res := <-workers[results]
-			ops = ops + res.ops
-			db.WriteErrors = db.WriteErrors + res.writeErrors
-			db.ReadErrors = db.ReadErrors + res.readErrors
-		}
+func (db *PostgresDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	_, err = db.session.ExecContext(ctx, "DELETE FROM benchmark_db_sessions WHERE name = $1", SessionName)
+	return err
+}
 
-	}
+func (db *PostgresDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	var qry string
 
-	elapsedTime = time.Now().Unix() - StartTest
-	if elapsedTime > 0 {
-		TPS = ops / elapsedTime
+	qry = fmt.Sprintf("INSERT INTO benchmark_db_%s%d(data, id) VALUES($1, $2) ON CONFLICT (id) DO UPDATE SET data = $1", SessionName, loop)
+	row, qerr := db.session.QueryContext(ctx, qry, data, key)
+	if qerr != nil {
+		fmt.Printf("Write error: '%s'\n", qerr)
+		return qerr
+	} else {
+		row.Close()
 	}
 
-	showStats(db.ReadErrors, db.WriteErrors, ops, elapsedTime, TPS)
-	os.Exit(0)
 	return nil
 }
 
-func (db *PostgresDB) TestCycle(SessionName string, config config.Config, arguments arguments.Arguments, currentLoop int, wg *sync.WaitGroup, JunkData [][]string, JunkKey [][]string, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet) (err error) {
-	defer wg.Done()
+func (db *PostgresDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	var qry string
 
-	fmt.Printf("Loop %d: Beginning Write Test...\n", currentLoop+1)
-	StartLoop := time.Now()
-	for iter := 0; iter < arguments.Iterations; iter++ {
-		StartWrite := time.Now()
-		if err := writeTestData(SessionName, db, currentLoop, JunkKey[currentLoop][iter], JunkData[currentLoop][iter]); err != nil {
-			fmt.Printf("Loop: %d, Iteration: %d --  %s\n", currentLoop+1, iter+1, err)
-			db.WriteErrors++
-		}
-		StopWrite := time.Since(StartWrite)
-		write_stats.Add(StopWrite)
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id = $1 LIMIT 1", SessionName, loop)
+	err = db.session.QueryRowContext(ctx, qry, key).Scan(&data)
+	if err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
 	}
-	StopLoop := time.Since(StartLoop)
+	return data, nil
+}
 
-	fmt.Printf("Loop %d: Write Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
+func (db *PostgresDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	var qry string
+	var value string
 
-	if arguments.Mode == "w" {
-		return
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id >= $1 ORDER BY id LIMIT $2", SessionName, loop)
+	rows, err := db.session.QueryContext(ctx, qry, startKey, count)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// READ DATA AND VERIFY
-	fmt.Printf("Loop %d: Beginning Read and Verify...\n", currentLoop+1)
-	StartLoop = time.Now()
-	for iter := 0; iter < arguments.Iterations; iter++ {
-		StartRead := time.Now()
-		data, err := readTestData(SessionName, db, currentLoop, JunkKey[currentLoop][iter])
-		if err != nil {
-			fmt.Printf("Loop %d, Iteration: %d -- %s\n", currentLoop+1, iter+1, err)
-			db.ReadErrors++
-		}
-		StopRead := time.Since(StartRead)
-		read_stats.Add(StopRead)
-
-		if err := checkData(JunkData[currentLoop][iter], data, arguments.NoDataCheck); err != nil {
-			fmt.Printf("Loop %d, Iteration: %d\n%s", currentLoop+1, iter+1, err)
-			db.ReadErrors++
+	for rows.Next() {
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
 		}
+		data = append(data, value)
 	}
-	StopLoop = time.Since(StartLoop)
-	fmt.Printf("Loop %d: Read Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
 
-	return nil
+	return data, rows.Err()
+}
+
+func (db *PostgresDB) Close() (err error) {
+	return db.session.Close()
 }
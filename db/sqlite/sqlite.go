@@ -0,0 +1,315 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	_ "modernc.org/sqlite"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var JournalModes = []string{"WAL", "DELETE", "MEMORY", "TRUNCATE", "PERSIST", "OFF"}
+var SynchronousLevels = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+
+type SQLiteDB struct {
+	session *sql.DB
+}
+
+func New() *SQLiteDB {
+	var tmp SQLiteDB = SQLiteDB{}
+	return &tmp
+}
+
+func valid(value string, values []string) bool {
+	for _, v := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// dsn builds the DSN of the database file. The pragmas are set on every
+// connection of the pool, most of them only apply to the connection.
+func dsn(path string, config config.SQLite, timeout time.Duration) (string, error) {
+	// CONCURRENT WRITERS WAIT FOR THE LOCK INSTEAD OF FAILING WITH SQLITE_BUSY
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", timeout.Milliseconds()))
+	if len(config.JournalMode) > 0 {
+		if !valid(config.JournalMode, JournalModes) {
+			return "", fmt.Errorf("Unknown journal mode: %s (%s)", config.JournalMode, strings.Join(JournalModes, ", "))
+		}
+		pragmas.Add("_pragma", fmt.Sprintf("journal_mode(%s)", config.JournalMode))
+	}
+	if len(config.Synchronous) > 0 {
+		if !valid(config.Synchronous, SynchronousLevels) {
+			return "", fmt.Errorf("Unknown synchronous level: %s (%s)", config.Synchronous, strings.Join(SynchronousLevels, ", "))
+		}
+		pragmas.Add("_pragma", fmt.Sprintf("synchronous(%s)", config.Synchronous))
+	}
+	if config.CacheSize != 0 {
+		pragmas.Add("_pragma", fmt.Sprintf("cache_size(%d)", config.CacheSize))
+	}
+	return path + "?" + pragmas.Encode(), nil
+}
+
+func (db *SQLiteDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	path := config.SQLite.Path
+	if len(path) == 0 {
+		path = "benchmark_db.sqlite"
+	}
+	options, err := dsn(path, config.SQLite, timeout)
+	if err != nil {
+		fmt.Printf("Invalid sqlite configuration: '%s'\n", err.Error())
+		return err
+	}
+
+	fmt.Printf("sqlite file: %s\n", path)
+	if db.session, err = sql.Open("sqlite", options); err != nil {
+		fmt.Printf("Failed to create session: '%s'\n", err.Error())
+		return err
+	}
+
+	// THE SETTINGS IN USE, SQLITE KEEPS ITS JOURNAL MODE WHEN THE FILE DOESN'T SUPPORT THE ONE ASKED
+	var journal string
+	var synchronous int
+	var cache int
+	err = db.session.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journal)
+	if err == nil {
+		err = db.session.QueryRowContext(ctx, "PRAGMA synchronous").Scan(&synchronous)
+	}
+	if err == nil {
+		err = db.session.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cache)
+	}
+	if err != nil {
+		fmt.Printf("Failed to connect: '%s'\n", err.Error())
+		db.session.Close()
+		return err
+	}
+	fmt.Printf("journal_mode: %s, synchronous: %s, cache_size: %d\n", journal, SynchronousLevels[synchronous], cache)
+
+	fmt.Printf("Connection to database was successful!\n")
+
+	return nil
+}
+
+func (db *SQLiteDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	var records int = 0
+
+	JunkData = make([][]string, len(config.Patterns))
+	JunkKey = make([][]string, len(config.Patterns))
+
+	fmt.Printf("Reading Pattern Data...")
+	for session := range config.Patterns {
+		fmt.Printf("%s...", config.Patterns[session])
+		JunkKey[session], JunkData[session], err = db.readPattern(ctx, config.Patterns[session])
+		if err != nil {
+			fmt.Printf("Failed to retrieve record(s): '%s'\n", err.Error())
+			return nil, nil, err
+		}
+		records += len(JunkKey[session])
+		fmt.Printf("(%d records)...", len(JunkKey[session]))
+	}
+	fmt.Printf("Done! (%d records total)\n", records)
+	return JunkKey, JunkData, nil
+}
+
+func (db *SQLiteDB) readPattern(ctx context.Context, table string) (keys []string, values []string, err error) {
+	var id string
+	var data string
+
+	rows, err := db.session.QueryContext(ctx, fmt.Sprintf("SELECT id, data FROM %s", table))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&id, &data); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, id)
+		values = append(values, data)
+	}
+	return keys, values, rows.Err()
+}
+
+func (db *SQLiteDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var qry string
+
+	fmt.Printf("Creating test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		qry = fmt.Sprintf("DROP TABLE IF EXISTS benchmark_db_%s%d", SessionName, iter)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
+		}
+
+		qry = fmt.Sprintf("CREATE TABLE benchmark_db_%s%d (id text PRIMARY KEY, data text)", SessionName, iter)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error creating test table:\n%s\n", err)
+			return err
+		}
+
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+// ListTestTables returns the test tables of the database, registered or not.
+func (db *SQLiteDB) ListTestTables(ctx context.Context) (tables []string, err error) {
+	var name string
+
+	rows, err := db.session.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(name, "benchmark_db_") && name != "benchmark_db_sessions" {
+			tables = append(tables, name)
+		}
+	}
+	return tables, rows.Err()
+}
+
+func (db *SQLiteDB) DropTestTable(ctx context.Context, table string) (err error) {
+	_, err = db.session.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+	return err
+}
+
+func (db *SQLiteDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	var qry string
+
+	qry = fmt.Sprintf("DELETE FROM benchmark_db_%s%d WHERE id = ?", SessionName, loop)
+	if _, err = db.session.ExecContext(ctx, qry, key); err != nil {
+		fmt.Printf("Delete error: '%s'\n", err)
+		return err
+	}
+	return nil
+}
+
+func (db *SQLiteDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	var qry string
+
+	fmt.Printf("Dropping test tables...")
+
+	for iter := 0; iter < nb_tables; iter++ {
+		qry = fmt.Sprintf("DROP TABLE IF EXISTS benchmark_db_%s%d", SessionName, iter)
+		if _, err = db.session.ExecContext(ctx, qry); err != nil {
+			fmt.Printf("Fatal Error dropping test table:\n%s\n", err)
+			return err
+		}
+		fmt.Printf("%d.", iter+1)
+	}
+	fmt.Printf("  Success.\n")
+
+	return nil
+}
+
+func (db *SQLiteDB) createSessionTable(ctx context.Context) (err error) {
+	_, err = db.session.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS benchmark_db_sessions (name text PRIMARY KEY, info text)")
+	return err
+}
+
+func (db *SQLiteDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	if err = db.createSessionTable(ctx); err != nil {
+		return err
+	}
+	_, err = db.session.ExecContext(ctx, "INSERT INTO benchmark_db_sessions(name, info) VALUES(?, ?) ON CONFLICT (name) DO UPDATE SET info = excluded.info", SessionName, info)
+	return err
+}
+
+func (db *SQLiteDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	var name string
+	var info string
+
+	if err = db.createSessionTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.session.QueryContext(ctx, "SELECT name, info FROM benchmark_db_sessions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions = make(map[string]string)
+	for rows.Next() {
+		if err = rows.Scan(&name, &info); err != nil {
+			return nil, err
+		}
+		sessions[name] = info
+	}
+
+	return sessions, rows.Err()
+}
+
+func (db *SQLiteDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	_, err = db.session.ExecContext(ctx, "DELETE FROM benchmark_db_sessions WHERE name = ?", SessionName)
+	return err
+}
+
+func (db *SQLiteDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	var qry string
+
+	qry = fmt.Sprintf("INSERT INTO benchmark_db_%s%d(id, data) VALUES(?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data", SessionName, loop)
+	if _, err = db.session.ExecContext(ctx, qry, key, data); err != nil {
+		fmt.Printf("Write error: '%s'\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db *SQLiteDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	var qry string
+
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id = ? LIMIT 1", SessionName, loop)
+	err = db.session.QueryRowContext(ctx, qry, key).Scan(&data)
+	if err != nil {
+		fmt.Printf("Read error: '%s'\n", err)
+		return "", err
+	}
+	return data, nil
+}
+
+func (db *SQLiteDB) ScanData(ctx context.Context, SessionName string, loop int, startKey string, count int) (data []string, err error) {
+	var qry string
+	var value string
+
+	qry = fmt.Sprintf("SELECT data FROM benchmark_db_%s%d WHERE id >= ? ORDER BY id LIMIT ?", SessionName, loop)
+	rows, err := db.session.QueryContext(ctx, qry, startKey, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		data = append(data, value)
+	}
+
+	return data, rows.Err()
+}
+
+func (db *SQLiteDB) Close() (err error) {
+	return db.session.Close()
+}
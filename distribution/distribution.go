@@ -0,0 +1,271 @@
+package distribution
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// ZipfianConstant is the skew YCSB uses by default.
+const ZipfianConstant = 0.99
+
+// Names lists the distributions New knows.
+var Names = []string{"uniform", "zipfian", "scrambledzipfian", "hotspot", "latest", "sequential"}
+
+// Options are the parameters of the skewed distributions.
+type Options struct {
+	Theta       float64 // ZIPFIAN SKEW, BETWEEN 0 AND 1 (EXCLUDED)
+	HotFraction float64 // FRACTION OF THE ITEMS THAT ARE HOT
+	HotOps      float64 // FRACTION OF THE OPERATIONS THAT GO TO THE HOT ITEMS
+}
+
+var DefaultOptions = Options{Theta: ZipfianConstant, HotFraction: 0.2, HotOps: 0.8}
+
+func (options Options) Validate() (err error) {
+	if options.Theta <= 0 || options.Theta >= 1 {
+		return fmt.Errorf("The zipfian theta must be between 0 and 1 (excluded)")
+	}
+	if options.HotFraction <= 0 || options.HotFraction > 1 || options.HotOps < 0 || options.HotOps > 1 {
+		return fmt.Errorf("The hot fractions must be between 0 and 1")
+	}
+	return nil
+}
+
+// Generator picks the next item out of items (0 to items-1). The number of
+// items can grow between calls as records are inserted.
+type Generator interface {
+	Next(random *rand.Rand, items int64) int64
+}
+
+// New returns the generator of a distribution name. zipfian favours the
+// lowest items, scrambledzipfian spreads the same popularity over all the
+// items and latest favours the highest items.
+func New(name string, options Options) (generator Generator, err error) {
+	if err = options.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "uniform":
+		return NewUniform(), nil
+	case "zipfian":
+		return NewZipfian(options.Theta), nil
+	case "scrambledzipfian":
+		return NewScrambledZipfian(options.Theta), nil
+	case "hotspot":
+		return NewHotspot(options.HotFraction, options.HotOps), nil
+	case "latest":
+		return NewLatest(options.Theta), nil
+	case "sequential":
+		return NewSequential(), nil
+	}
+	return nil, fmt.Errorf("Unknown request distribution: %s", name)
+}
+
+type Uniform struct{}
+
+func NewUniform() *Uniform {
+	var tmp Uniform = Uniform{}
+	return &tmp
+}
+
+func (uniform *Uniform) Next(random *rand.Rand, items int64) int64 {
+	if items <= 0 {
+		return 0
+	}
+	return random.Int63n(items)
+}
+
+// zetaCacheSize is the number of item counts a Zipfian keeps the zeta
+// constant of.
+const zetaCacheSize = 16
+
+// Zipfian favours the low items: item 0 is the most popular, item 1 the
+// next and so on. The zeta constant is extended incrementally when the
+// number of items grows, as in YCSB's ZipfianGenerator, and kept for the
+// last item counts used so that callers alternating between a few counts
+// don't recompute it on every call.
+type Zipfian struct {
+	theta float64
+	alpha float64
+	zeta2 float64
+	items int64
+	zetan float64
+	eta   float64
+	zetas map[int64]cachedZeta
+	calls uint64
+	mutex sync.Mutex
+}
+
+type cachedZeta struct {
+	zetan float64
+	used  uint64
+}
+
+func NewZipfian(theta float64) *Zipfian {
+	var tmp Zipfian = Zipfian{}
+	tmp.theta = theta
+	tmp.alpha = 1.0 / (1.0 - theta)
+	tmp.zeta2 = zeta(0, 2, theta, 0)
+	tmp.zetas = make(map[int64]cachedZeta)
+	return &tmp
+}
+
+// zeta adds the terms from+1 to to of the zeta series to sum.
+func zeta(from int64, to int64, theta float64, sum float64) float64 {
+	for i := from; i < to; i++ {
+		sum += 1 / math.Pow(float64(i+1), theta)
+	}
+	return sum
+}
+
+func (zipfian *Zipfian) Next(random *rand.Rand, items int64) int64 {
+	if items <= 1 {
+		return 0
+	}
+
+	zipfian.mutex.Lock()
+	zipfian.calls++
+	if items != zipfian.items {
+		if cached, ok := zipfian.zetas[items]; ok {
+			zipfian.zetan = cached.zetan
+		} else if items > zipfian.items {
+			zipfian.zetan = zeta(zipfian.items, items, zipfian.theta, zipfian.zetan)
+		} else {
+			zipfian.zetan = zeta(0, items, zipfian.theta, 0)
+		}
+		zipfian.items = items
+		zipfian.eta = (1 - math.Pow(2.0/float64(items), 1-zipfian.theta)) / (1 - zipfian.zeta2/zipfian.zetan)
+		zipfian.cache()
+	}
+	zetan := zipfian.zetan
+	eta := zipfian.eta
+	zipfian.mutex.Unlock()
+
+	u := random.Float64()
+	uz := u * zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, zipfian.theta) {
+		return 1
+	}
+	item := int64(float64(items) * math.Pow(eta*u-eta+1, zipfian.alpha))
+	if item >= items {
+		item = items - 1
+	}
+	return item
+}
+
+// cache keeps the zeta constant of the current item count, dropping the
+// least recently used count when the cache is full.
+func (zipfian *Zipfian) cache() {
+	if _, ok := zipfian.zetas[zipfian.items]; !ok && len(zipfian.zetas) >= zetaCacheSize {
+		var oldest int64
+		var used uint64 = math.MaxUint64
+		for items, cached := range zipfian.zetas {
+			if cached.used < used {
+				oldest, used = items, cached.used
+			}
+		}
+		delete(zipfian.zetas, oldest)
+	}
+	zipfian.zetas[zipfian.items] = cachedZeta{zetan: zipfian.zetan, used: zipfian.calls}
+}
+
+// ScrambledZipfian has the popularity of Zipfian but spreads the popular
+// items over the whole key space by hashing them.
+type ScrambledZipfian struct {
+	zipfian *Zipfian
+}
+
+func NewScrambledZipfian(theta float64) *ScrambledZipfian {
+	var tmp ScrambledZipfian = ScrambledZipfian{}
+	tmp.zipfian = NewZipfian(theta)
+	return &tmp
+}
+
+func (scrambled *ScrambledZipfian) Next(random *rand.Rand, items int64) int64 {
+	if items <= 1 {
+		return 0
+	}
+	return int64(fnv64(uint64(scrambled.zipfian.Next(random, items))) % uint64(items))
+}
+
+// Latest favours the most recently inserted items.
+type Latest struct {
+	zipfian *Zipfian
+}
+
+func NewLatest(theta float64) *Latest {
+	var tmp Latest = Latest{}
+	tmp.zipfian = NewZipfian(theta)
+	return &tmp
+}
+
+func (latest *Latest) Next(random *rand.Rand, items int64) int64 {
+	if items <= 1 {
+		return 0
+	}
+	return items - 1 - latest.zipfian.Next(random, items)
+}
+
+// Hotspot sends hotOps of the operations to the first hotFraction of the
+// items and the rest to the other items, uniformly within each set.
+type Hotspot struct {
+	hotFraction float64
+	hotOps      float64
+}
+
+func NewHotspot(hotFraction float64, hotOps float64) *Hotspot {
+	var tmp Hotspot = Hotspot{}
+	tmp.hotFraction = hotFraction
+	tmp.hotOps = hotOps
+	return &tmp
+}
+
+func (hotspot *Hotspot) Next(random *rand.Rand, items int64) int64 {
+	if items <= 1 {
+		return 0
+	}
+	hot := int64(float64(items) * hotspot.hotFraction)
+	if hot < 1 {
+		hot = 1
+	}
+	if hot >= items || random.Float64() < hotspot.hotOps {
+		return random.Int63n(hot)
+	}
+	return hot + random.Int63n(items-hot)
+}
+
+// Sequential walks the items in order, wrapping around at the end. The
+// position is shared by all the workers using the generator.
+type Sequential struct {
+	next int64
+}
+
+func NewSequential() *Sequential {
+	var tmp Sequential = Sequential{}
+	return &tmp
+}
+
+func (sequential *Sequential) Next(random *rand.Rand, items int64) int64 {
+	if items <= 1 {
+		return 0
+	}
+	return (atomic.AddInt64(&sequential.next, 1) - 1) % items
+}
+
+// fnv64 is the FNV-1a hash of the 8 bytes of value, as used by YCSB to
+// scramble the zipfian items.
+func fnv64(value uint64) uint64 {
+	hash := uint64(0xcbf29ce484222325)
+	for j := 0; j < 8; j++ {
+		hash ^= value & 0xff
+		hash *= 0x100000001b3
+		value >>= 8
+	}
+	return hash
+}
@@ -0,0 +1,136 @@
+package distribution
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	for _, name := range Names {
+		generator, err := New(name, DefaultOptions)
+		if err != nil {
+			t.Fatalf("New(%s): %s", name, err)
+		}
+		random := rand.New(rand.NewSource(1))
+		for _, items := range []int64{0, 1, 2, 10, 1000, 3, 1000000, 10} {
+			for j := 0; j < 1000; j++ {
+				item := generator.Next(random, items)
+				if item < 0 || (items > 0 && item >= items) || (items <= 0 && item != 0) {
+					t.Fatalf("%s: Next(%d) = %d, out of range", name, items, item)
+				}
+			}
+		}
+	}
+}
+
+func TestSkew(t *testing.T) {
+	const items = 1000
+	const samples = 100000
+
+	tests := []struct {
+		name    string
+		hot     func(item int64) bool
+		minimum float64
+		maximum float64
+	}{
+		// THE 10% LOWEST ITEMS GET ABOUT 10% OF THE OPERATIONS UNIFORMLY, MOST OF THEM WITH ZIPFIAN
+		{"uniform", func(item int64) bool { return item < items/10 }, 0.08, 0.12},
+		{"zipfian", func(item int64) bool { return item < items/10 }, 0.6, 1},
+		{"latest", func(item int64) bool { return item >= items-items/10 }, 0.6, 1},
+		{"hotspot", func(item int64) bool { return item < items/5 }, 0.78, 0.82},
+		{"scrambledzipfian", func(item int64) bool { return item < items/10 }, 0.02, 0.3},
+	}
+
+	for _, test := range tests {
+		generator, err := New(test.name, DefaultOptions)
+		if err != nil {
+			t.Fatalf("New(%s): %s", test.name, err)
+		}
+		random := rand.New(rand.NewSource(1))
+		hot := 0
+		for j := 0; j < samples; j++ {
+			if test.hot(generator.Next(random, items)) {
+				hot++
+			}
+		}
+		if share := float64(hot) / samples; share < test.minimum || share > test.maximum {
+			t.Errorf("%s: %.3f of the operations went to the hot items, want %.2f to %.2f", test.name, share,
+				test.minimum, test.maximum)
+		}
+	}
+}
+
+func TestSequential(t *testing.T) {
+	sequential := NewSequential()
+	for j := int64(0); j < 25; j++ {
+		if item := sequential.Next(nil, 10); item != j%10 {
+			t.Fatalf("Next = %d, want %d", item, j%10)
+		}
+	}
+}
+
+// TestZipfianChangingItems checks that a generator used with changing item
+// counts picks the same items as generators dedicated to each count.
+func TestZipfianChangingItems(t *testing.T) {
+	counts := []int64{1000, 500000, 1000, 500000, 2000, 1000, 1000000, 500000}
+
+	shared := NewZipfian(ZipfianConstant)
+	sharedRandom := rand.New(rand.NewSource(1))
+	dedicated := make(map[int64]*Zipfian)
+	dedicatedRandom := rand.New(rand.NewSource(1))
+
+	for j := 0; j < 1000; j++ {
+		items := counts[j%len(counts)]
+		if dedicated[items] == nil {
+			dedicated[items] = NewZipfian(ZipfianConstant)
+		}
+		item := shared.Next(sharedRandom, items)
+		want := dedicated[items].Next(dedicatedRandom, items)
+		if item != want {
+			t.Fatalf("call %d: Next(%d) = %d, want %d", j, items, item, want)
+		}
+	}
+
+	if expected := zeta(0, 500000, ZipfianConstant, 0); math.Abs(shared.zetas[500000].zetan-expected) > 1e-9*expected {
+		t.Errorf("zeta(500000) = %v, want %v", shared.zetas[500000].zetan, expected)
+	}
+}
+
+// TestZipfianAlternatingItems checks that alternating between two item
+// counts doesn't recompute the zeta constant on every call, which made
+// every call cost as much as the item count.
+func TestZipfianAlternatingItems(t *testing.T) {
+	zipfian := NewZipfian(ZipfianConstant)
+	random := rand.New(rand.NewSource(1))
+	zipfian.Next(random, 1000000)
+	zipfian.Next(random, 500000)
+
+	start := time.Now()
+	for j := 0; j < 10000; j++ {
+		zipfian.Next(random, 500000)
+		zipfian.Next(random, 1000000)
+	}
+	// RECOMPUTING THE CONSTANT TAKES MILLISECONDS, 20000 TIMES WOULD TAKE MINUTES
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("20000 calls alternating the item count took %v", elapsed)
+	}
+}
+
+func TestZipfianGrowingItems(t *testing.T) {
+	zipfian := NewZipfian(ZipfianConstant)
+	random := rand.New(rand.NewSource(1))
+
+	// THE FIXED COUNT STAYS CACHED WHILE THE GROWING ONES GO THROUGH THE CACHE
+	for items := int64(100000); items < 100000+10*zetaCacheSize; items++ {
+		zipfian.Next(random, 50000)
+		zipfian.Next(random, items)
+		if len(zipfian.zetas) > zetaCacheSize {
+			t.Fatalf("%d item counts cached, want at most %d", len(zipfian.zetas), zetaCacheSize)
+		}
+		if _, ok := zipfian.zetas[50000]; !ok {
+			t.Fatalf("the zeta of the alternated item count was dropped")
+		}
+	}
+}
@@ -3,8 +3,14 @@ module github.com/hartsp2000/benchmark_db
 go 1.16
 
 require (
-	github.com/gocql/gocql v0.0.0-20210707082121-9a3953d1826d // indirect
-	github.com/lib/pq v1.10.2 // indirect
-	github.com/tsuna/gohbase v0.0.0-20210721183200-2b1c330433e3 // indirect
-	gopkg.in/redis.v5 v5.2.9 // indirect
+	github.com/cockroachdb/pebble v0.0.0-20220322140420-6e19e39957fb
+	github.com/dgraph-io/badger/v3 v3.2103.2
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/gocql/gocql v0.0.0-20210707082121-9a3953d1826d
+	github.com/lib/pq v1.10.2
+	github.com/tsuna/gohbase v0.0.0-20210721183200-2b1c330433e3
+	go.etcd.io/bbolt v1.3.6
+	go.mongodb.org/mongo-driver v1.7.3
+	modernc.org/sqlite v1.14.6
 )
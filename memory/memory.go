@@ -1,5 +0,0 @@
-package memory
-
-type Memory struct {
-	Loop, Iter int
-}
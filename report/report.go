@@ -0,0 +1,229 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"github.com/hartsp2000/benchmark_db/statistics"
+	"github.com/hartsp2000/benchmark_db/version"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Latency struct {
+	Count  uint64 `json:"count"`
+	Mean   int64  `json:"mean_ns"`
+	Stddev int64  `json:"stddev_ns"`
+	P50    int64  `json:"p50_ns"`
+	P90    int64  `json:"p90_ns"`
+	P99    int64  `json:"p99_ns"`
+	P999   int64  `json:"p99_9_ns"`
+	P9999  int64  `json:"p99_99_ns"`
+	Max    int64  `json:"max_ns"`
+}
+
+type Operation struct {
+	Name    string  `json:"name"`
+	Latency Latency `json:"latency"`
+}
+
+// Stage is the result of one stage of a -profile.
+type Stage struct {
+	Rate       float64     `json:"rate_ops_per_second"`
+	Target     float64     `json:"target_ops_per_second"`
+	Workers    int         `json:"workers"`
+	Hold       float64     `json:"hold_seconds"`
+	Operations int64       `json:"operations"`
+	Errors     int         `json:"errors"`
+	Elapsed    float64     `json:"elapsed_seconds"`
+	Throughput float64     `json:"throughput_ops_per_second"`
+	SLO        string      `json:"slo,omitempty"`
+	Latencies  []Operation `json:"latencies"`
+}
+
+// Document is the machine readable result of a benchmark run.
+type Document struct {
+	Version      string              `json:"version"`
+	BuildID      string              `json:"build_id"`
+	SessionName  string              `json:"session_name"`
+	DB_Type      string              `json:"db_type"`
+	Started      time.Time           `json:"started"`
+	Arguments    arguments.Arguments `json:"arguments"`
+	Config       config.Config       `json:"config"`
+	Operations   int64               `json:"operations"`
+	ReadErrors   int                 `json:"read_errors"`
+	WriteErrors  int                 `json:"write_errors"`
+	VerifyErrors int                 `json:"verify_errors"`
+	Elapsed      float64             `json:"elapsed_seconds"`
+	Throughput   float64             `json:"throughput_ops_per_second"`
+	Latencies    []Operation         `json:"latencies"`
+	Violations   []string            `json:"threshold_violations"`
+	Stages       []Stage             `json:"stages,omitempty"`
+	MaxSustained float64             `json:"max_sustained_ops_per_second,omitempty"`
+
+	// THE -warmup OPERATIONS, THEIR LATENCIES ARE THE warmup_* ONES
+	WarmupOperations int64   `json:"warmup_operations,omitempty"`
+	WarmupErrors     int     `json:"warmup_errors,omitempty"`
+	WarmupElapsed    float64 `json:"warmup_elapsed_seconds,omitempty"`
+}
+
+func NewLatency(stats *statistics.DurationSet) Latency {
+	var latency Latency
+	latency.Count = stats.Count()
+	latency.Mean = stats.Mean().Nanoseconds()
+	latency.Stddev = stats.Stddev().Nanoseconds()
+	latency.P50 = stats.Percentile(50).Nanoseconds()
+	latency.P90 = stats.Percentile(90).Nanoseconds()
+	latency.P99 = stats.Percentile(99).Nanoseconds()
+	latency.P999 = stats.Percentile(99.9).Nanoseconds()
+	latency.P9999 = stats.Percentile(99.99).Nanoseconds()
+	latency.Max = stats.Max().Nanoseconds()
+	return latency
+}
+
+func New(SessionName string, config config.Config, arguments arguments.Arguments, started time.Time) *Document {
+	var tmp Document = Document{}
+	tmp.Version = version.VERSION
+	tmp.BuildID = version.BUILDID
+	tmp.SessionName = SessionName
+	tmp.DB_Type = arguments.DB_Type
+	tmp.Started = started
+	tmp.Arguments = arguments
+	tmp.Config = config.Redacted()
+	return &tmp
+}
+
+func (doc *Document) SetTotals(ops int64, elapsed time.Duration, readErrors int, writeErrors int) {
+	doc.Operations = ops
+	doc.ReadErrors = readErrors
+	doc.WriteErrors = writeErrors
+	doc.Elapsed = elapsed.Seconds()
+	if doc.Elapsed > 0 {
+		doc.Throughput = float64(ops) / doc.Elapsed
+	}
+}
+
+func (doc *Document) AddLatency(name string, stats *statistics.DurationSet) {
+	doc.Latencies = append(doc.Latencies, Operation{Name: name, Latency: NewLatency(stats)})
+}
+
+func (stage *Stage) AddLatency(name string, stats *statistics.DurationSet) {
+	stage.Latencies = append(stage.Latencies, Operation{Name: name, Latency: NewLatency(stats)})
+}
+
+func (doc *Document) AddStage(stage Stage) {
+	doc.Stages = append(doc.Stages, stage)
+}
+
+// Save writes the document in the format selected with -output to the file
+// selected with -out-file ("-" is stdout). Nothing is written for the
+// default text output.
+func (doc *Document) Save() (err error) {
+	var out io.Writer = os.Stdout
+
+	if doc.Arguments.Output == "text" {
+		return nil
+	}
+
+	if doc.Arguments.OutFile != "-" {
+		filename := doc.Arguments.OutFile
+		if len(filename) == 0 {
+			filename = fmt.Sprintf("benchmark_db_%s.%s", doc.SessionName, doc.Arguments.Output)
+		}
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+		fmt.Printf("Results written to %s\n", filename)
+	}
+
+	switch doc.Arguments.Output {
+	case "json":
+		return doc.WriteJSON(out)
+	case "csv":
+		return doc.WriteCSV(out)
+	}
+	return fmt.Errorf("Unknown output format: %s", doc.Arguments.Output)
+}
+
+func (doc *Document) WriteJSON(out io.Writer) (err error) {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(doc)
+}
+
+// WriteCSV writes one row per operation type, then one per operation type of
+// every -profile stage (with the stage columns set). The run metadata and
+// every argument are repeated on each row so that rows can be loaded on
+// their own.
+func (doc *Document) WriteCSV(out io.Writer) (err error) {
+	writer := csv.NewWriter(out)
+
+	header := []string{"version", "build_id", "session_name", "db_type", "started"}
+	values := []string{doc.Version, doc.BuildID, doc.SessionName, doc.DB_Type, doc.Started.Format(time.RFC3339)}
+
+	args := reflect.ValueOf(doc.Arguments)
+	for j := 0; j < args.NumField(); j++ {
+		header = append(header, "arg_"+args.Type().Field(j).Name)
+		values = append(values, fmt.Sprintf("%v", args.Field(j).Interface()))
+	}
+
+	cfg, err := json.Marshal(doc.Config)
+	if err != nil {
+		return err
+	}
+
+	header = append(header, "config", "operations", "read_errors", "write_errors", "verify_errors", "threshold_violations",
+		"elapsed_seconds", "throughput_ops_per_second",
+		"operation", "count", "mean_ns", "stddev_ns", "p50_ns", "p90_ns", "p99_ns", "p99_9_ns", "p99_99_ns", "max_ns",
+		"stage", "stage_rate_ops_per_second", "stage_target_ops_per_second", "stage_workers", "stage_operations",
+		"stage_errors", "stage_throughput_ops_per_second", "stage_slo", "max_sustained_ops_per_second")
+	values = append(values, string(cfg), strconv.FormatInt(doc.Operations, 10), strconv.Itoa(doc.ReadErrors),
+		strconv.Itoa(doc.WriteErrors), strconv.Itoa(doc.VerifyErrors), strings.Join(doc.Violations, "; "),
+		strconv.FormatFloat(doc.Elapsed, 'f', 3, 64), strconv.FormatFloat(doc.Throughput, 'f', 3, 64))
+	maxSustained := strconv.FormatFloat(doc.MaxSustained, 'f', 3, 64)
+
+	if err = writer.Write(header); err != nil {
+		return err
+	}
+
+	stageValues := make([]string, 8)
+	for _, operation := range doc.Latencies {
+		if err = writer.Write(latencyRow(values, operation, append(stageValues, maxSustained))); err != nil {
+			return err
+		}
+	}
+
+	for j, stage := range doc.Stages {
+		stageValues = []string{strconv.Itoa(j + 1), strconv.FormatFloat(stage.Rate, 'f', 3, 64),
+			strconv.FormatFloat(stage.Target, 'f', 3, 64), strconv.Itoa(stage.Workers),
+			strconv.FormatInt(stage.Operations, 10), strconv.Itoa(stage.Errors), strconv.FormatFloat(stage.Throughput, 'f', 3, 64),
+			stage.SLO}
+		for _, operation := range stage.Latencies {
+			if err = writer.Write(latencyRow(values, operation, append(stageValues, maxSustained))); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func latencyRow(values []string, operation Operation, stageValues []string) (row []string) {
+	latency := operation.Latency
+	row = append([]string{}, values...)
+	row = append(row, operation.Name, strconv.FormatUint(latency.Count, 10))
+	for _, value := range []int64{latency.Mean, latency.Stddev, latency.P50, latency.P90, latency.P99, latency.P999, latency.P9999, latency.Max} {
+		row = append(row, strconv.FormatInt(value, 10))
+	}
+	return append(row, stageValues...)
+}
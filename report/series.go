@@ -0,0 +1,66 @@
+package report
+
+import (
+	"encoding/csv"
+	"github.com/hartsp2000/benchmark_db/statistics"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Series is the csv file of the -report-interval time series, one row per
+// interval.
+type Series struct {
+	started time.Time
+	file    *os.File
+	writer  *csv.Writer
+}
+
+func NewSeries(filename string, started time.Time) (series *Series, err error) {
+	var tmp Series = Series{}
+	tmp.started = started
+	if tmp.file, err = os.Create(filename); err != nil {
+		return nil, err
+	}
+	tmp.writer = csv.NewWriter(tmp.file)
+
+	err = tmp.writer.Write([]string{"time", "elapsed_seconds", "interval_seconds", "phase", "operations", "errors",
+		"throughput_ops_per_second", "count", "mean_ns", "stddev_ns", "p50_ns", "p90_ns", "p99_ns", "p99_9_ns", "p99_99_ns", "max_ns"})
+	if err != nil {
+		tmp.file.Close()
+		return nil, err
+	}
+	return &tmp, nil
+}
+
+// Add writes the row of an interval ending at end. The phase is warmup or
+// measured.
+func (series *Series) Add(end time.Time, length time.Duration, phase string, operations int64, errors int, stats *statistics.DurationSet) (err error) {
+	latency := NewLatency(stats)
+	var throughput float64
+	if length > 0 {
+		throughput = float64(operations) / length.Seconds()
+	}
+
+	row := []string{end.Format(time.RFC3339Nano), strconv.FormatFloat(end.Sub(series.started).Seconds(), 'f', 3, 64),
+		strconv.FormatFloat(length.Seconds(), 'f', 3, 64), phase, strconv.FormatInt(operations, 10), strconv.Itoa(errors),
+		strconv.FormatFloat(throughput, 'f', 3, 64), strconv.FormatUint(latency.Count, 10)}
+	for _, value := range []int64{latency.Mean, latency.Stddev, latency.P50, latency.P90, latency.P99, latency.P999, latency.P9999, latency.Max} {
+		row = append(row, strconv.FormatInt(value, 10))
+	}
+	if err = series.writer.Write(row); err != nil {
+		return err
+	}
+	// FLUSH EVERY ROW SO THAT THE FILE CAN BE FOLLOWED WHILE THE TEST RUNS
+	series.writer.Flush()
+	return series.writer.Error()
+}
+
+func (series *Series) Close() (err error) {
+	series.writer.Flush()
+	if err = series.writer.Error(); err != nil {
+		series.file.Close()
+		return err
+	}
+	return series.file.Close()
+}
@@ -0,0 +1,205 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/db"
+	"github.com/hartsp2000/benchmark_db/version"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Info is the record kept in the database's session registry for every
+// session that created test tables. The arguments are kept so that a reused
+// session runs with the same dataset parameters.
+type Info struct {
+	Name      string              `json:"name"`
+	Created   time.Time           `json:"created"`
+	Version   string              `json:"version"`
+	BuildID   string              `json:"build_id"`
+	Tables    int                 `json:"tables"`
+	Rows      int64               `json:"rows"`
+	Loaded    time.Time           `json:"loaded,omitempty"`
+	Arguments arguments.Arguments `json:"arguments"`
+}
+
+func New(SessionName string, arguments arguments.Arguments) *Info {
+	var tmp Info = Info{}
+	tmp.Name = SessionName
+	tmp.Created = time.Now().UTC()
+	tmp.Version = version.VERSION
+	tmp.BuildID = version.BUILDID
+	tmp.Tables = arguments.Loops
+	tmp.Rows = int64(arguments.Loops) * int64(arguments.Iterations)
+	tmp.Arguments = arguments
+	return &tmp
+}
+
+func (info *Info) Age() time.Duration {
+	return time.Since(info.Created)
+}
+
+func (info *Info) String() string {
+	loaded := "not loaded"
+	if !info.Loaded.IsZero() {
+		loaded = "loaded " + info.Loaded.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%s: created %s (%v ago) by version %s, %d tables, %d rows (%s), loops: %d, iterations: %d, "+
+		"key size: %d bytes, data size: %d bytes", info.Name, info.Created.Format(time.RFC3339),
+		info.Age().Round(time.Second), info.Version, info.Tables, info.Rows, loaded, info.Arguments.Loops,
+		info.Arguments.Iterations, info.Arguments.KeyBS, info.Arguments.DataBS)
+}
+
+// Register records the session in the registry so that it can be found by
+// Drop and Purge later.
+func Register(ctx context.Context, idb db.Interface_DB, info *Info) (err error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return idb.SaveSession(ctx, info.Name, string(data))
+}
+
+// List returns every registered session, oldest first.
+func List(ctx context.Context, idb db.Interface_DB) (sessions []Info, err error) {
+	records, err := idb.LoadSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, record := range records {
+		var info Info
+		if err = json.Unmarshal([]byte(record), &info); err != nil {
+			fmt.Printf("Ignoring unreadable registry entry for session %s: %s\n", name, err)
+			continue
+		}
+		info.Name = name
+		sessions = append(sessions, info)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Created.Before(sessions[j].Created) })
+	return sessions, nil
+}
+
+// Find returns the registry record of a session, or nil when the session
+// isn't registered.
+func Find(ctx context.Context, idb db.Interface_DB, SessionName string) (info *Info, err error) {
+	sessions, err := List(ctx, idb)
+	if err != nil {
+		return nil, err
+	}
+	for j := range sessions {
+		if sessions[j].Name == SessionName {
+			return &sessions[j], nil
+		}
+	}
+	return nil, nil
+}
+
+// Print lists the sessions, one per line.
+func Print(sessions []Info) {
+	fmt.Printf("%d registered sessions:\n", len(sessions))
+	for j := range sessions {
+		fmt.Printf("    %s\n", &sessions[j])
+	}
+}
+
+// Drop removes the tables (or keys) of a session and its registry entry.
+// The table count is taken from the registry when the session is known
+// there, otherwise nb_tables is used.
+func Drop(ctx context.Context, idb db.Interface_DB, SessionName string, nb_tables int) (err error) {
+	info, err := Find(ctx, idb, SessionName)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		nb_tables = info.Tables
+	}
+
+	fmt.Printf("%s: ", SessionName)
+	if err = idb.DropTestTables(ctx, SessionName, nb_tables); err != nil {
+		return err
+	}
+	return idb.DeleteSession(ctx, SessionName)
+}
+
+// Purge lists every registered session and drops the ones older than age.
+// The test tables of the sessions missing from the registry are listed too,
+// when the driver can enumerate them, and dropped only when unregistered is
+// set since their age is unknown.
+func Purge(ctx context.Context, idb db.Interface_DB, age time.Duration, unregistered bool) (purged int, err error) {
+	sessions, err := List(ctx, idb)
+	if err != nil {
+		return 0, err
+	}
+
+	Print(sessions)
+
+	lister, ok := idb.(db.TableLister)
+	var tables []string
+	if ok {
+		if tables, err = Unregistered(ctx, lister, sessions); err != nil {
+			return 0, err
+		}
+		fmt.Printf("%d unregistered test tables:\n", len(tables))
+		for _, table := range tables {
+			fmt.Printf("    %s\n", table)
+		}
+	} else {
+		fmt.Printf("This database can't list the unregistered test tables, drop them with -drop -session NAME -loops N\n")
+	}
+
+	for j := range sessions {
+		if sessions[j].Age() <= age {
+			continue
+		}
+		if err = Drop(ctx, idb, sessions[j].Name, sessions[j].Tables); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	fmt.Printf("Purged %d sessions older than %v\n", purged, age)
+
+	if len(tables) == 0 {
+		return purged, nil
+	}
+	if !unregistered {
+		fmt.Printf("Kept the unregistered test tables, -purge-unregistered drops them\n")
+		return purged, nil
+	}
+	for _, table := range tables {
+		if err = lister.DropTestTable(ctx, table); err != nil {
+			return purged, err
+		}
+	}
+	fmt.Printf("Dropped %d unregistered test tables\n", len(tables))
+	return purged, nil
+}
+
+// Unregistered returns the test tables that belong to none of the registered
+// sessions. Names are compared regardless of case since some databases fold
+// the unquoted table names.
+func Unregistered(ctx context.Context, lister db.TableLister, sessions []Info) (tables []string, err error) {
+	all, err := lister.ListTestTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make(map[string]bool)
+	for j := range sessions {
+		for table := 0; table < sessions[j].Tables; table++ {
+			registered[strings.ToLower(fmt.Sprintf("%s%s%d", db.TestTablePrefix, sessions[j].Name, table))] = true
+		}
+	}
+
+	for _, table := range all {
+		if !registered[strings.ToLower(table)] {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
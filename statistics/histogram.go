@@ -0,0 +1,206 @@
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// The histogram keeps 2^sub_bucket_bits linear sub-buckets for every power
+// of two, which gives a relative precision better than 0.1% (3 significant
+// digits) from one nanosecond up to the largest time.Duration.
+const sub_bucket_bits = 11
+const sub_bucket_count = 1 << sub_bucket_bits
+const sub_bucket_half = sub_bucket_count / 2
+
+var Percentiles = []float64{50, 90, 99, 99.9, 99.99}
+
+// Histogram is a high dynamic range latency histogram. It is safe for
+// concurrent use and histograms recorded by separate workers can be
+// combined with Merge.
+type Histogram struct {
+	counts  []uint64
+	count   uint64
+	minimum int64
+	maximum int64
+	sum     float64
+	mutex   sync.Mutex
+}
+
+func NewHistogram() *Histogram {
+	var tmp Histogram = Histogram{}
+	return &tmp
+}
+
+func bucketIndex(value int64) int {
+	if value < sub_bucket_count {
+		return int(value)
+	}
+	exponent := bits.Len64(uint64(value)) - sub_bucket_bits
+	sub := int(value >> uint(exponent))
+	return sub_bucket_count + (exponent-1)*sub_bucket_half + (sub - sub_bucket_half)
+}
+
+// highestEquivalentValue returns the largest value that is recorded in the
+// same bucket as index.
+func highestEquivalentValue(index int) int64 {
+	if index < sub_bucket_count {
+		return int64(index)
+	}
+	exponent := (index-sub_bucket_count)/sub_bucket_half + 1
+	sub := int64((index-sub_bucket_count)%sub_bucket_half + sub_bucket_half)
+	return (sub << uint(exponent)) + (int64(1) << uint(exponent)) - 1
+}
+
+func (histogram *Histogram) Record(t time.Duration) {
+	value := t.Nanoseconds()
+	if value < 0 {
+		value = 0
+	}
+	index := bucketIndex(value)
+
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+
+	if index >= len(histogram.counts) {
+		counts := make([]uint64, index+1)
+		copy(counts, histogram.counts)
+		histogram.counts = counts
+	}
+	histogram.counts[index]++
+
+	if histogram.count == 0 || value < histogram.minimum {
+		histogram.minimum = value
+	}
+	if value > histogram.maximum {
+		histogram.maximum = value
+	}
+	histogram.sum += float64(value)
+	histogram.count++
+}
+
+func (histogram *Histogram) Merge(other *Histogram) {
+	if other == nil || other == histogram {
+		return
+	}
+
+	other.mutex.Lock()
+	counts := make([]uint64, len(other.counts))
+	copy(counts, other.counts)
+	count := other.count
+	minimum := other.minimum
+	maximum := other.maximum
+	sum := other.sum
+	other.mutex.Unlock()
+
+	if count == 0 {
+		return
+	}
+
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+
+	if len(counts) > len(histogram.counts) {
+		grown := make([]uint64, len(counts))
+		copy(grown, histogram.counts)
+		histogram.counts = grown
+	}
+	for j := range counts {
+		histogram.counts[j] += counts[j]
+	}
+
+	if histogram.count == 0 || minimum < histogram.minimum {
+		histogram.minimum = minimum
+	}
+	if maximum > histogram.maximum {
+		histogram.maximum = maximum
+	}
+	histogram.sum += sum
+	histogram.count += count
+}
+
+func (histogram *Histogram) Count() uint64 {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	return histogram.count
+}
+
+func (histogram *Histogram) Min() time.Duration {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	return time.Duration(histogram.minimum)
+}
+
+func (histogram *Histogram) Max() time.Duration {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	return time.Duration(histogram.maximum)
+}
+
+func (histogram *Histogram) Mean() time.Duration {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	if histogram.count == 0 {
+		return 0
+	}
+	return time.Duration(histogram.sum / float64(histogram.count))
+}
+
+// Percentile returns the latency below which the given percentage (0-100)
+// of the recorded values fall.
+func (histogram *Histogram) Percentile(percentile float64) time.Duration {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	return histogram.percentile(percentile)
+}
+
+func (histogram *Histogram) percentile(percentile float64) time.Duration {
+	if histogram.count == 0 {
+		return 0
+	}
+	if percentile > 100 {
+		percentile = 100
+	}
+
+	target := uint64(math.Ceil(percentile / 100 * float64(histogram.count)))
+	if target < 1 {
+		target = 1
+	}
+
+	var total uint64
+	for j := range histogram.counts {
+		total += histogram.counts[j]
+		if total >= target {
+			value := highestEquivalentValue(j)
+			if value > histogram.maximum {
+				value = histogram.maximum
+			}
+			return time.Duration(value)
+		}
+	}
+	return time.Duration(histogram.maximum)
+}
+
+func (histogram *Histogram) Reset() {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	histogram.counts = nil
+	histogram.count = 0
+	histogram.minimum = 0
+	histogram.maximum = 0
+	histogram.sum = 0
+}
+
+func (histogram *Histogram) String() string {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+
+	result := ""
+	for _, percentile := range Percentiles {
+		result += fmt.Sprintf("p%v: %v, ", percentile, histogram.percentile(percentile))
+	}
+	result += fmt.Sprintf("Max: %v\n", time.Duration(histogram.maximum))
+	return result
+}
@@ -0,0 +1,74 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		value int64
+		index int
+	}{
+		{0, 0},
+		{1, 1},
+		{sub_bucket_count - 1, sub_bucket_count - 1},
+		{sub_bucket_count, sub_bucket_count},
+		{sub_bucket_count + 1, sub_bucket_count},
+		{sub_bucket_count + 2, sub_bucket_count + 1},
+		{2*sub_bucket_count - 1, sub_bucket_count + sub_bucket_half - 1},
+		{2 * sub_bucket_count, sub_bucket_count + sub_bucket_half},
+		{4*sub_bucket_count - 1, sub_bucket_count + 2*sub_bucket_half - 1},
+	}
+
+	for _, test := range tests {
+		if index := bucketIndex(test.value); index != test.index {
+			t.Errorf("bucketIndex(%d) = %d, want %d", test.value, index, test.index)
+		}
+	}
+}
+
+func TestBucketIndexPrecision(t *testing.T) {
+	values := []int64{0, 1, 1000, 2047, 2048, 4095, 4096, 123456, 1e9, 3600e9, 1<<62 + 12345}
+
+	for _, value := range values {
+		index := bucketIndex(value)
+		highest := highestEquivalentValue(index)
+		if highest < value {
+			t.Errorf("highestEquivalentValue(bucketIndex(%d)) = %d, below the value", value, highest)
+		}
+		if float64(highest-value) > float64(value)/1000 {
+			t.Errorf("highestEquivalentValue(bucketIndex(%d)) = %d, more than 0.1%% off", value, highest)
+		}
+		if value > 0 && bucketIndex(value-1) > index {
+			t.Errorf("bucketIndex(%d) > bucketIndex(%d)", value-1, value)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	histogram := NewHistogram()
+	for j := 1; j <= 1000; j++ {
+		histogram.Record(time.Duration(j) * time.Microsecond)
+	}
+
+	tests := []struct {
+		percentile float64
+		latency    time.Duration
+	}{
+		{50, 500 * time.Microsecond},
+		{90, 900 * time.Microsecond},
+		{99, 990 * time.Microsecond},
+		{100, 1000 * time.Microsecond},
+	}
+
+	for _, test := range tests {
+		latency := histogram.Percentile(test.percentile)
+		if latency < test.latency || float64(latency-test.latency) > float64(test.latency)/1000 {
+			t.Errorf("Percentile(%v) = %v, want %v", test.percentile, latency, test.latency)
+		}
+	}
+	if histogram.Count() != 1000 || histogram.Min() != time.Microsecond || histogram.Max() != time.Millisecond {
+		t.Errorf("Count, Min, Max = %d, %v, %v", histogram.Count(), histogram.Min(), histogram.Max())
+	}
+}
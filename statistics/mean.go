@@ -7,31 +7,63 @@ import (
 	"time"
 )
 
+// DurationSet keeps the running mean and standard deviation of a set of
+// durations together with a Histogram of the values for the percentiles.
+// The running values are kept as float64 so that large nanosecond values
+// neither lose precision nor overflow.
 type DurationSet struct {
 	count              uint64
-	variance           uint64
-	mean               uint64
-	square_mean        uint64
-	standard_deviation uint64
+	mean               float64
+	square_diff        float64
+	standard_deviation float64
+	histogram          Histogram
 	mutex              sync.Mutex
 }
 
 func (duration_set *DurationSet) Add(t time.Duration) {
-	var t_value uint64
+	var t_value float64
 
 	duration_set.mutex.Lock()
 	defer duration_set.mutex.Unlock()
 
-	t_value = uint64(t.Nanoseconds())
+	t_value = float64(t.Nanoseconds())
 
-	duration_set.mean = ((duration_set.mean * duration_set.count) + t_value) / (duration_set.count + 1)
+	duration_set.count++
+	delta := t_value - duration_set.mean
+	duration_set.mean += delta / float64(duration_set.count)
+	duration_set.square_diff += delta * (t_value - duration_set.mean)
+	duration_set.standard_deviation = math.Sqrt(duration_set.square_diff / float64(duration_set.count))
+
+	duration_set.histogram.Record(t)
+}
 
-	duration_set.square_mean = ((duration_set.square_mean * duration_set.count) + (t_value * t_value)) / (duration_set.count + 1)
+// Merge adds all the values recorded in other to this set.
+func (duration_set *DurationSet) Merge(other *DurationSet) {
+	if other == nil || other == duration_set {
+		return
+	}
 
-	duration_set.variance = duration_set.square_mean - (duration_set.mean * duration_set.mean)
-	duration_set.standard_deviation = uint64(math.Sqrt(float64(duration_set.variance)))
+	other.mutex.Lock()
+	count := other.count
+	mean := other.mean
+	square_diff := other.square_diff
+	other.mutex.Unlock()
 
-	duration_set.count++
+	if count == 0 {
+		return
+	}
+
+	duration_set.mutex.Lock()
+	defer duration_set.mutex.Unlock()
+
+	total := duration_set.count + count
+	delta := mean - duration_set.mean
+	duration_set.square_diff += square_diff + delta*delta*float64(duration_set.count)*float64(count)/float64(total)
+	duration_set.mean += delta * float64(count) / float64(total)
+	duration_set.count = total
+	duration_set.standard_deviation = math.Sqrt(duration_set.square_diff / float64(duration_set.count))
+
+	duration_set.histogram.Merge(&other.histogram)
 }
 
 func (duration_set *DurationSet) Mean() time.Duration {
@@ -58,14 +90,26 @@ func (duration_set *DurationSet) Stddev() time.Duration {
 	return result
 }
 
+func (duration_set *DurationSet) Percentile(percentile float64) time.Duration {
+	return duration_set.histogram.Percentile(percentile)
+}
+
+func (duration_set *DurationSet) Max() time.Duration {
+	return duration_set.histogram.Max()
+}
+
+func (duration_set *DurationSet) Histogram() *Histogram {
+	return &duration_set.histogram
+}
+
 func (duration_set *DurationSet) Reset() {
 	duration_set.mutex.Lock()
 	defer duration_set.mutex.Unlock()
 	duration_set.count = 0
 	duration_set.mean = 0
-	duration_set.square_mean = 0
-	duration_set.variance = 0
+	duration_set.square_diff = 0
 	duration_set.standard_deviation = 0
+	duration_set.histogram.Reset()
 }
 
 func (duration_set *DurationSet) String() string {
@@ -73,5 +117,5 @@ func (duration_set *DurationSet) String() string {
 	defer duration_set.mutex.Unlock()
 	mean := time.Duration(duration_set.mean)
 	standard_deviation := time.Duration(duration_set.standard_deviation)
-	return fmt.Sprintf("Total: %d, Mean: %v, Standard Deviation: %v\n", duration_set.count, mean, standard_deviation)
+	return fmt.Sprintf("Total: %d, Mean: %v, Standard Deviation: %v\n    %s", duration_set.count, mean, standard_deviation, &duration_set.histogram)
 }
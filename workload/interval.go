@@ -0,0 +1,151 @@
+package workload
+
+import (
+	"sync"
+	"time"
+)
+
+// IntervalReport receives the results of every -report-interval, ending at
+// end and lasting length, in order, along with the results of its -warmup
+// operations.
+type IntervalReport func(end time.Time, length time.Duration, results *Results, warmup *Results)
+
+// intervals collects the results of the workers per -report-interval. The
+// workers hand over their results when they move to a new interval; results
+// handed over after their interval was reported count in the next one. The
+// merged intervals are the results of the test.
+type intervals struct {
+	start   time.Time
+	length  time.Duration
+	report  IntervalReport
+	buckets map[int64]*Results
+	warm    map[int64]*Results
+	next    int64
+	total   *Results
+	warmup  *Results
+	mutex   sync.Mutex
+	stop    chan bool
+	done    chan bool
+}
+
+func newIntervals(length time.Duration, report IntervalReport) *intervals {
+	var tmp intervals = intervals{}
+	tmp.start = time.Now()
+	tmp.length = length
+	tmp.report = report
+	tmp.buckets = make(map[int64]*Results)
+	tmp.warm = make(map[int64]*Results)
+	tmp.total = NewResults()
+	tmp.warmup = NewResults()
+	tmp.stop = make(chan bool)
+	tmp.done = make(chan bool)
+	go tmp.run()
+	return &tmp
+}
+
+func (iv *intervals) index(t time.Time) int64 {
+	return int64(t.Sub(iv.start) / iv.length)
+}
+
+// add merges the results of a worker into the interval they were collected
+// in, or the next one to report if that one was already reported.
+func (iv *intervals) add(res *Results) {
+	iv.mutex.Lock()
+	defer iv.mutex.Unlock()
+	target := res.interval
+	if target < iv.next {
+		target = iv.next
+	}
+	buckets := iv.buckets
+	if res.warmup {
+		buckets = iv.warm
+	}
+	bucket, ok := buckets[target]
+	if !ok {
+		bucket = NewResults()
+		buckets[target] = bucket
+	}
+	bucket.Merge(res)
+}
+
+// roll hands the results of a worker over when it moved to a new interval
+// and returns the results to collect the next operations in.
+func (iv *intervals) roll(res *Results) *Results {
+	current := iv.index(time.Now())
+	if current == res.interval {
+		return res
+	}
+	iv.add(res)
+	fresh := NewResults()
+	fresh.interval = current
+	fresh.warmup = res.warmup
+	return fresh
+}
+
+// run reports every interval shortly after it ends, to give the workers
+// busy with an operation at the end of the interval the time to roll.
+func (iv *intervals) run() {
+	defer close(iv.done)
+	for {
+		timer := time.NewTimer(time.Until(iv.start.Add(time.Duration(iv.next+1)*iv.length + iv.length/10)))
+		select {
+		case <-iv.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		iv.flush(iv.next)
+	}
+}
+
+// flush reports the intervals up to last.
+func (iv *intervals) flush(last int64) {
+	iv.mutex.Lock()
+	defer iv.mutex.Unlock()
+	for ; iv.next <= last; iv.next++ {
+		bucket, ok := iv.buckets[iv.next]
+		if !ok {
+			bucket = NewResults()
+		}
+		warm, ok := iv.warm[iv.next]
+		if !ok {
+			warm = NewResults()
+		}
+		delete(iv.buckets, iv.next)
+		delete(iv.warm, iv.next)
+
+		end := iv.start.Add(time.Duration(iv.next+1) * iv.length)
+		if now := time.Now(); end.After(now) {
+			end = now
+		}
+		bucket.Duration = end.Sub(iv.start.Add(time.Duration(iv.next) * iv.length))
+		warm.Duration = bucket.Duration
+		iv.total.Merge(bucket)
+		iv.warmup.Merge(warm)
+		iv.report(end, bucket.Duration, bucket, warm)
+	}
+}
+
+// close reports the last intervals once the workers handed over their
+// final results and returns the merged results of all the intervals, and
+// of their warm-up operations.
+func (iv *intervals) close() (total *Results, warmup *Results) {
+	close(iv.stop)
+	<-iv.done
+
+	last := iv.index(time.Now())
+	iv.mutex.Lock()
+	for index := range iv.buckets {
+		if index > last {
+			last = index
+		}
+	}
+	for index := range iv.warm {
+		if index > last {
+			last = index
+		}
+	}
+	iv.mutex.Unlock()
+	iv.flush(last)
+	return iv.total, iv.warmup
+}
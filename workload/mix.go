@@ -0,0 +1,121 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// DefaultMix is the read/write split of the rw TPS test when -mix isn't set.
+const DefaultMix = "read=0.5,insert=0.5"
+
+// DefaultStoredMix is the read/write split of the rw TPS test of -stored
+// patterns, which have no new records to insert.
+const DefaultStoredMix = "read=0.5,update=0.5"
+
+// DefaultScanLength is the most records a scan of -mix reads.
+const DefaultScanLength = 100
+
+// mixOperations maps the -mix names to the operations, in reporting order.
+var mixOperations = []struct {
+	name      string
+	operation string
+}{
+	{"read", OpRead},
+	{"insert", OpInsert},
+	{"update", OpUpdate},
+	{"delete", OpDelete},
+	{"rmw", OpReadModifyWrite},
+	{"scan", OpScan},
+}
+
+type proportion struct {
+	operation string
+	value     float64
+}
+
+// Mix is the proportion of every operation of a workload. The proportions
+// don't need to add up to 1, they are relative to their total.
+type Mix struct {
+	proportions []proportion
+	total       float64
+}
+
+// ParseMix parses a -mix list such as "read=0.95,update=0.05". The names are
+// read, insert, update, delete, rmw (read-modify-write) and scan.
+func ParseMix(spec string) (mix *Mix, err error) {
+	values := make(map[string]float64)
+
+	for _, part := range strings.Split(spec, ",") {
+		fields := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid -mix entry %q, expected name=proportion", part)
+		}
+		operation := ""
+		for _, known := range mixOperations {
+			if strings.ToLower(fields[0]) == known.name {
+				operation = known.operation
+			}
+		}
+		if len(operation) == 0 {
+			return nil, fmt.Errorf("Unknown -mix operation: %s", fields[0])
+		}
+		if values[operation], err = strconv.ParseFloat(fields[1], 64); err != nil {
+			return nil, fmt.Errorf("Invalid -mix proportion for %s: %s", fields[0], err)
+		}
+	}
+
+	return newMix(values)
+}
+
+// newMix builds a mix from the proportions of the operations.
+func newMix(values map[string]float64) (mix *Mix, err error) {
+	var tmp Mix = Mix{}
+
+	for _, known := range mixOperations {
+		value := values[known.operation]
+		if value < 0 {
+			return nil, fmt.Errorf("Operation proportions can't be negative")
+		}
+		if value > 0 {
+			tmp.proportions = append(tmp.proportions, proportion{known.operation, value})
+			tmp.total += value
+		}
+	}
+
+	if tmp.total <= 0 {
+		return nil, fmt.Errorf("The operation mix is empty")
+	}
+	return &tmp, nil
+}
+
+// choose picks the next operation according to the proportions.
+func (mix *Mix) choose(random *rand.Rand) string {
+	value := random.Float64() * mix.total
+	for _, operation := range mix.proportions {
+		if value < operation.value {
+			return operation.operation
+		}
+		value -= operation.value
+	}
+	return mix.proportions[len(mix.proportions)-1].operation
+}
+
+// Has reports whether the mix runs the operation.
+func (mix *Mix) Has(operation string) bool {
+	for _, known := range mix.proportions {
+		if known.operation == operation {
+			return true
+		}
+	}
+	return false
+}
+
+func (mix *Mix) String() string {
+	var parts []string
+	for _, operation := range mix.proportions {
+		parts = append(parts, fmt.Sprintf("%s=%.4g", operation.operation, operation.value/mix.total))
+	}
+	return strings.Join(parts, ", ")
+}
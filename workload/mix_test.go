@@ -0,0 +1,76 @@
+package workload
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		spec   string
+		shares map[string]float64
+		err    bool
+	}{
+		{"read=0.5,insert=0.5", map[string]float64{OpRead: 0.5, OpInsert: 0.5}, false},
+		{"read=95, update=5", map[string]float64{OpRead: 0.95, OpUpdate: 0.05}, false},
+		{"READ=1,delete=1,rmw=1,scan=1", map[string]float64{OpRead: 0.25, OpDelete: 0.25, OpReadModifyWrite: 0.25,
+			OpScan: 0.25}, false},
+		{"read=1,update=0", map[string]float64{OpRead: 1}, false},
+		{"read", nil, true},
+		{"write=1", nil, true},
+		{"read=x", nil, true},
+		{"read=-1,update=2", nil, true},
+		{"read=0", nil, true},
+	}
+
+	for _, test := range tests {
+		mix, err := ParseMix(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseMix(%q) = %s, want an error", test.spec, mix)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMix(%q): %s", test.spec, err)
+			continue
+		}
+		for _, known := range mixOperations {
+			share := 0.0
+			for _, operation := range mix.proportions {
+				if operation.operation == known.operation {
+					share = operation.value / mix.total
+				}
+			}
+			if math.Abs(share-test.shares[known.operation]) > 1e-9 {
+				t.Errorf("ParseMix(%q): %s share %v, want %v", test.spec, known.operation, share,
+					test.shares[known.operation])
+			}
+			if mix.Has(known.operation) != (test.shares[known.operation] > 0) {
+				t.Errorf("ParseMix(%q).Has(%s) = %v", test.spec, known.operation, mix.Has(known.operation))
+			}
+		}
+	}
+}
+
+func TestMixChoose(t *testing.T) {
+	const samples = 100000
+
+	mix, err := ParseMix("read=0.5,insert=0.2,update=0.1,delete=0.1,rmw=0.05,scan=0.05")
+	if err != nil {
+		t.Fatalf("ParseMix: %s", err)
+	}
+	random := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for j := 0; j < samples; j++ {
+		counts[mix.choose(random)]++
+	}
+
+	for _, operation := range mix.proportions {
+		want := operation.value / mix.total
+		if share := float64(counts[operation.operation]) / samples; math.Abs(share-want) > 0.01 {
+			t.Errorf("%s chosen %.3f of the time, want %.3f", operation.operation, share, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRampStages is the number of stages a ramp is reported in when the
+// -profile doesn't give one.
+const DefaultRampStages = 10
+
+// Stage is one step of a load profile: the target rate (going from Rate to
+// Target over the Hold when they differ, 0 is a closed loop) and the number
+// of workers (0 is -tw).
+type Stage struct {
+	Rate    float64
+	Target  float64
+	Workers int
+	Hold    time.Duration
+}
+
+func (stage Stage) String() string {
+	load := "closed loop"
+	if stage.Rate > 0 && stage.Rate != stage.Target {
+		load = fmt.Sprintf("%.0f-%.0f ops/sec", stage.Rate, stage.Target)
+	} else if stage.Rate > 0 {
+		load = fmt.Sprintf("%.0f ops/sec", stage.Rate)
+	}
+	return fmt.Sprintf("%s, %d workers, %v", load, stage.Workers, stage.Hold)
+}
+
+// StageResults are the results of one stage of a profile. SLO is the
+// verdict of a -search stage: "pass" or the reason it failed.
+type StageResults struct {
+	Stage   Stage
+	Results Results
+	SLO     string
+}
+
+// ParseProfile parses a -profile:
+//
+//	ramp:FROM-TO@DURATION[/STAGES]    rate going from FROM to TO ops/sec
+//	steps:RATE@HOLD,RATE@HOLD,...     rate steps
+//	workers:N@HOLD,N@HOLD,...         concurrency steps (at -rate, if set)
+func ParseProfile(spec string) (stages []Stage, err error) {
+	fields := strings.SplitN(spec, ":", 2)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Invalid -profile %q, expected ramp:, steps: or workers:", spec)
+	}
+
+	switch fields[0] {
+	case "ramp":
+		return parseRamp(fields[1])
+	case "steps", "workers":
+		for _, step := range strings.Split(fields[1], ",") {
+			value, hold, err := parseStep(step)
+			if err != nil {
+				return nil, err
+			}
+			if fields[0] == "steps" {
+				stages = append(stages, Stage{Rate: value, Target: value, Hold: hold})
+			} else if workers := int(value); float64(workers) == value {
+				stages = append(stages, Stage{Workers: workers, Hold: hold})
+			} else {
+				return nil, fmt.Errorf("Invalid -profile worker count: %v", value)
+			}
+		}
+		return stages, nil
+	}
+	return nil, fmt.Errorf("Unknown -profile type: %s (ramp, steps or workers)", fields[0])
+}
+
+// parseStep parses a VALUE@HOLD step of a profile.
+func parseStep(step string) (value float64, hold time.Duration, err error) {
+	fields := strings.SplitN(strings.TrimSpace(step), "@", 2)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("Invalid -profile step %q, expected value@duration", step)
+	}
+	if value, err = strconv.ParseFloat(fields[0], 64); err != nil || value <= 0 {
+		return 0, 0, fmt.Errorf("Invalid -profile value %q, must be above 0", fields[0])
+	}
+	if hold, err = time.ParseDuration(fields[1]); err != nil || hold <= 0 {
+		return 0, 0, fmt.Errorf("Invalid -profile duration %q", fields[1])
+	}
+	return value, hold, nil
+}
+
+// parseRamp splits a FROM-TO@DURATION[/STAGES] ramp into stages that each
+// ramp over their part of the rates.
+func parseRamp(spec string) (stages []Stage, err error) {
+	count := DefaultRampStages
+	if fields := strings.SplitN(spec, "/", 2); len(fields) == 2 {
+		if count, err = strconv.Atoi(fields[1]); err != nil || count < 1 {
+			return nil, fmt.Errorf("Invalid -profile ramp stage count %q", fields[1])
+		}
+		spec = fields[0]
+	}
+
+	fields := strings.SplitN(spec, "-", 2)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Invalid -profile ramp %q, expected ramp:FROM-TO@DURATION[/STAGES]", spec)
+	}
+	from, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || from <= 0 {
+		return nil, fmt.Errorf("Invalid -profile ramp rate %q, must be above 0", fields[0])
+	}
+	to, duration, err := parseStep(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	for j := 0; j < count; j++ {
+		stages = append(stages, Stage{
+			Rate:   from + (to-from)*float64(j)/float64(count),
+			Target: from + (to-from)*float64(j+1)/float64(count),
+			Hold:   duration / time.Duration(count),
+		})
+	}
+	return stages, nil
+}
+
+// RunProfile runs a test once per stage of a profile, each run stopped at
+// the end of the stage's hold, and returns the merged results along with
+// the results of every stage.
+func (engine *Engine) RunProfile(ctx context.Context, stages []Stage, run func(ctx context.Context) (Results, error)) (results Results, profile []StageResults, err error) {
+	saved := engine.arguments
+	defer func() {
+		engine.arguments = saved
+		engine.stage = nil
+	}()
+
+	results = *NewResults()
+	for j := range stages {
+		stage := stages[j]
+		if ctx.Err() != nil {
+			break
+		}
+		if stage.Workers == 0 {
+			stage.Workers = saved.TpsWorkers
+		}
+		fmt.Printf("\nStage %d/%d: %s\n", j+1, len(stages), stage)
+
+		// THE STAGE ENDS THE TEST, -dur ONLY NEEDS TO OUTLAST IT
+		engine.stage = &stage
+		engine.arguments.Duration = int(stage.Hold/time.Minute) + 1
+		engine.arguments.TpsWorkers = stage.Workers
+
+		stageCtx, cancel := context.WithTimeout(ctx, stage.Hold)
+		res, err := run(stageCtx)
+		cancel()
+		if err != nil {
+			return results, profile, err
+		}
+
+		fmt.Printf("\nStage %d/%d: %d operations, %.0f ops/sec, %d errors\n", j+1, len(stages), res.Ops, res.TPS(),
+			res.ReadErrors+res.WriteErrors+res.VerifyErrors)
+		profile = append(profile, StageResults{Stage: stage, Results: res})
+		results.Merge(&res)
+		results.Duration += res.Duration
+	}
+
+	return results, profile, nil
+}
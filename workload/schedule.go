@@ -0,0 +1,54 @@
+package workload
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Schedule hands out the intended start times of the operations of an open
+// loop test: one every 1/rate seconds from the start, shared by all the
+// workers, whatever the latency of the previous operations. An operation
+// that starts late is measured from its intended start time, which corrects
+// the coordinated omission of a closed loop test.
+type Schedule struct {
+	start time.Time
+	rate  float64
+	ramp  float64
+	end   time.Duration
+	next  int64
+}
+
+func NewSchedule(rate float64) *Schedule {
+	return NewRampSchedule(rate, rate, 0)
+}
+
+// NewRampSchedule is a schedule whose rate goes linearly from rate to
+// target over the duration.
+func NewRampSchedule(rate float64, target float64, duration time.Duration) *Schedule {
+	var tmp Schedule = Schedule{}
+	tmp.start = time.Now()
+	tmp.rate = rate
+	if duration > 0 {
+		tmp.ramp = (target - rate) / duration.Seconds()
+		tmp.end = duration
+	}
+	return &tmp
+}
+
+// Next returns the intended start time of the next operation.
+func (schedule *Schedule) Next() time.Time {
+	n := float64(atomic.AddInt64(&schedule.next, 1) - 1)
+
+	// THE nTH OPERATION STARTS WHEN rate*t + ramp*t*t/2 OPERATIONS WERE ISSUED
+	var seconds float64
+	if schedule.ramp == 0 {
+		seconds = n / schedule.rate
+	} else if square := schedule.rate*schedule.rate + 2*schedule.ramp*n; square >= 0 {
+		seconds = (math.Sqrt(square) - schedule.rate) / schedule.ramp
+	} else {
+		// A DOWNWARD RAMP PAST ITS END: NO MORE OPERATIONS UNTIL THE END OF THE STAGE
+		seconds = schedule.end.Seconds()
+	}
+	return schedule.start.Add(time.Duration(seconds * float64(time.Second)))
+}
@@ -0,0 +1,142 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchModes are the ways -search picks the rates it tries.
+var SearchModes = []string{"binary", "step"}
+
+// MinSustainedFraction is the part of its target rate a stage must reach
+// to pass: below it the workers could not keep up with the rate.
+const MinSustainedFraction = 0.9
+
+// SLO are the limits a stage of a -search must stay under.
+type SLO struct {
+	P99       time.Duration
+	ErrorRate float64
+}
+
+// Check returns "pass" when the results of a stage at the rate meet the
+// SLO, otherwise the reason they don't.
+func (slo SLO) Check(rate float64, results Results) string {
+	if p99 := results.Corrected.Percentile(99); slo.P99 > 0 && p99 > slo.P99 {
+		return fmt.Sprintf("p99 %v above %v", p99, slo.P99)
+	}
+	if slo.ErrorRate >= 0 && results.ErrorRate() > slo.ErrorRate {
+		return fmt.Sprintf("error rate %.4f above %.4f", results.ErrorRate(), slo.ErrorRate)
+	}
+	if results.TPS() < rate*MinSustainedFraction {
+		return fmt.Sprintf("throughput %.0f ops/sec below the rate", results.TPS())
+	}
+	return "pass"
+}
+
+// Search is a search for the highest rate, between Min and Max ops/sec,
+// that meets the SLO. Every rate tried runs for Hold. A binary search tries
+// Steps rates after the bounds, a step search tries Steps rates going up
+// from Min to Max until one fails.
+type Search struct {
+	Min   float64
+	Max   float64
+	Hold  time.Duration
+	Mode  string
+	Steps int
+	SLO   SLO
+}
+
+// NewSearch parses a MIN-MAX@HOLD -search range.
+func NewSearch(spec string, mode string, steps int, slo SLO) (search *Search, err error) {
+	var tmp Search = Search{}
+	tmp.Mode = mode
+	tmp.Steps = steps
+	tmp.SLO = slo
+
+	fields := strings.SplitN(spec, "-", 2)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Invalid -search %q, expected MIN-MAX@HOLD", spec)
+	}
+	if tmp.Min, err = strconv.ParseFloat(fields[0], 64); err != nil || tmp.Min <= 0 {
+		return nil, fmt.Errorf("Invalid -search rate %q, must be above 0", fields[0])
+	}
+	if tmp.Max, tmp.Hold, err = parseStep(fields[1]); err != nil {
+		return nil, err
+	}
+	if tmp.Max <= tmp.Min {
+		return nil, fmt.Errorf("The -search maximum rate must be above the minimum")
+	}
+	if !(mode == "binary" || mode == "step") {
+		return nil, fmt.Errorf("Unknown -search-mode: %s (%s)", mode, strings.Join(SearchModes, ", "))
+	}
+	if steps < 1 || (mode == "step" && steps < 2) {
+		return nil, fmt.Errorf("-search-steps must be at least 1 (2 for a step search)")
+	}
+	return &tmp, nil
+}
+
+// SearchRate runs the stages of a search and returns the results of all of
+// them, each with its SLO verdict.
+func (engine *Engine) SearchRate(ctx context.Context, search *Search, run func(ctx context.Context) (Results, error)) (results Results, stages []StageResults, err error) {
+	results = *NewResults()
+
+	// try runs one stage at the rate and tells if it met the SLO
+	try := func(rate float64) (passed bool, err error) {
+		res, tried, err := engine.RunProfile(ctx, []Stage{{Rate: rate, Target: rate, Hold: search.Hold}}, run)
+		if err != nil || len(tried) == 0 {
+			return false, err
+		}
+		tried[0].SLO = search.SLO.Check(rate, tried[0].Results)
+		fmt.Printf("Rate %.0f ops/sec: %s\n", rate, tried[0].SLO)
+		stages = append(stages, tried[0])
+		results.Merge(&res)
+		results.Duration += res.Duration
+		return tried[0].SLO == "pass", nil
+	}
+
+	switch search.Mode {
+	case "binary":
+		low, high := search.Min, search.Max
+		if passed, err := try(low); err != nil || !passed {
+			return results, stages, err
+		}
+		if passed, err := try(high); err != nil || passed {
+			return results, stages, err
+		}
+		for j := 0; j < search.Steps && ctx.Err() == nil; j++ {
+			rate := (low + high) / 2
+			passed, err := try(rate)
+			if err != nil {
+				return results, stages, err
+			}
+			if passed {
+				low = rate
+			} else {
+				high = rate
+			}
+		}
+	case "step":
+		for j := 0; j < search.Steps && ctx.Err() == nil; j++ {
+			rate := search.Min + (search.Max-search.Min)*float64(j)/float64(search.Steps-1)
+			if passed, err := try(rate); err != nil || !passed {
+				return results, stages, err
+			}
+		}
+	}
+
+	return results, stages, nil
+}
+
+// MaxSustainedRate is the highest rate of the stages that met their SLO, 0
+// when none did.
+func MaxSustainedRate(stages []StageResults) (rate float64) {
+	for _, stage := range stages {
+		if stage.SLO == "pass" && stage.Stage.Rate > rate {
+			rate = stage.Stage.Rate
+		}
+	}
+	return rate
+}
@@ -0,0 +1,74 @@
+package workload
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// warmup tells the workers when the -warmup at the start of a test is over
+// and collects the results of the operations run until then.
+type warmup struct {
+	until   time.Time
+	ops     int64
+	started int64
+	ended   int64
+	results *Results
+	mutex   sync.Mutex
+}
+
+// ParseWarmup parses a -warmup: a duration (eg: 30s) or a number of
+// operations (eg: 10000).
+func ParseWarmup(spec string) (duration time.Duration, ops int64, err error) {
+	if ops, err = strconv.ParseInt(spec, 10, 64); err == nil {
+		if ops < 0 {
+			return 0, 0, fmt.Errorf("Invalid -warmup: %s", spec)
+		}
+		return 0, ops, nil
+	}
+	if duration, err = time.ParseDuration(spec); err != nil || duration < 0 {
+		return 0, 0, fmt.Errorf("Invalid -warmup, expected a duration or a number of operations: %s", spec)
+	}
+	return duration, 0, nil
+}
+
+func newWarmup(duration time.Duration, ops int64) *warmup {
+	var tmp warmup = warmup{}
+	tmp.until = time.Now().Add(duration)
+	tmp.ops = ops
+	tmp.results = NewResults()
+	return &tmp
+}
+
+// active counts an operation about to start and tells if it is part of the
+// warm-up.
+func (w *warmup) active() bool {
+	if w.ops > 0 {
+		if atomic.AddInt64(&w.started, 1) <= w.ops {
+			return true
+		}
+		atomic.CompareAndSwapInt64(&w.ended, 0, time.Now().UnixNano())
+		return false
+	}
+	if time.Now().Before(w.until) {
+		return true
+	}
+	atomic.CompareAndSwapInt64(&w.ended, 0, w.until.UnixNano())
+	return false
+}
+
+// end is when the warm-up ended, zero while it lasts.
+func (w *warmup) end() time.Time {
+	if ended := atomic.LoadInt64(&w.ended); ended > 0 {
+		return time.Unix(0, ended)
+	}
+	return time.Time{}
+}
+
+func (w *warmup) add(res *Results) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.results.Merge(res)
+}
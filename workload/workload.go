@@ -0,0 +1,897 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"github.com/hartsp2000/benchmark_db/dataset"
+	"github.com/hartsp2000/benchmark_db/db"
+	"github.com/hartsp2000/benchmark_db/distribution"
+	"github.com/hartsp2000/benchmark_db/statistics"
+	"github.com/hartsp2000/benchmark_db/timeparse"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Engine runs the benchmark workloads against any db.Interface_DB driver.
+// It owns the worker scheduling, timing, data verification and the
+// aggregation of the results.
+type Engine struct {
+	db           db.Interface_DB
+	SessionName  string
+	config       config.Config
+	arguments    arguments.Arguments
+	data         dataset.Dataset
+	written      *dataset.Written
+	mix          *Mix
+	chooser      distribution.Generator
+	schedule     *Schedule
+	stage        *Stage
+	keys         *keyspace
+	inserts      []*keyspace
+	reserved     *reservations
+	prepared     bool
+	interval     time.Duration
+	report       IntervalReport
+	intervals    *intervals
+	warmup       *warmup
+	WriteErrors  int
+	ReadErrors   int
+	VerifyErrors int
+	mux          sync.Mutex
+}
+
+// Results is what a single worker, and the whole TPS test once the workers
+// are merged, hands back to the caller.
+type Results struct {
+	Ops          int64
+	Duration     time.Duration
+	ReadErrors   int
+	WriteErrors  int
+	VerifyErrors int
+	ReadStats    *statistics.DurationSet
+	WriteStats   *statistics.DurationSet
+	VerifyStats  *statistics.DurationSet
+
+	// LATENCIES AND ERRORS PER OPERATION TYPE OF THE MIXED WORKLOADS
+	OpStats  map[string]*statistics.DurationSet
+	OpErrors map[string]int
+
+	// LATENCIES OF ALL THE OPERATIONS OF A -rate TEST, FROM THE INTENDED
+	// (CORRECTED) AND THE ACTUAL (UNCORRECTED) START TIME
+	Corrected   *statistics.DurationSet
+	Uncorrected *statistics.DurationSet
+
+	// RESULTS OF THE -warmup OPERATIONS, NOT COUNTED IN THE OTHERS
+	Warmup *Results
+
+	// -report-interval THE RESULTS ARE COLLECTED IN, AND IF THEY ARE -warmup RESULTS
+	interval int64
+	warmup   bool
+}
+
+func NewResults() *Results {
+	res := new(Results)
+	res.ReadStats = &statistics.DurationSet{}
+	res.WriteStats = &statistics.DurationSet{}
+	res.VerifyStats = &statistics.DurationSet{}
+	res.OpStats = make(map[string]*statistics.DurationSet)
+	res.OpErrors = make(map[string]int)
+	res.Corrected = &statistics.DurationSet{}
+	res.Uncorrected = &statistics.DurationSet{}
+	return res
+}
+
+// Op returns the latencies of an operation type, creating them on first use.
+func (results *Results) Op(name string) *statistics.DurationSet {
+	stats, ok := results.OpStats[name]
+	if !ok {
+		stats = &statistics.DurationSet{}
+		results.OpStats[name] = stats
+	}
+	return stats
+}
+
+// OpNames returns the operation types that have latencies, sorted.
+func (results *Results) OpNames() (names []string) {
+	for name := range results.OpStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Merge adds the operations, errors and latencies of a worker to the totals.
+func (results *Results) Merge(res *Results) {
+	results.Ops = results.Ops + res.Ops
+	results.ReadErrors = results.ReadErrors + res.ReadErrors
+	results.WriteErrors = results.WriteErrors + res.WriteErrors
+	results.VerifyErrors = results.VerifyErrors + res.VerifyErrors
+	results.ReadStats.Merge(res.ReadStats)
+	results.WriteStats.Merge(res.WriteStats)
+	results.VerifyStats.Merge(res.VerifyStats)
+	for name, stats := range res.OpStats {
+		results.Op(name).Merge(stats)
+	}
+	for name, errors := range res.OpErrors {
+		results.OpErrors[name] += errors
+	}
+	results.Corrected.Merge(res.Corrected)
+	results.Uncorrected.Merge(res.Uncorrected)
+
+	// THE WARM-UPS OF SUCCESSIVE TESTS (-profile STAGES) ADD UP
+	if res.Warmup != nil {
+		if results.Warmup == nil {
+			results.Warmup = NewResults()
+		}
+		results.Warmup.Merge(res.Warmup)
+		results.Warmup.Duration += res.Warmup.Duration
+	}
+}
+
+// Latency is the latency of all the operations, from the intended start
+// times for the tests with a rate.
+func (results *Results) Latency() *statistics.DurationSet {
+	if results.Corrected.Count() > 0 {
+		return results.Corrected
+	}
+	latency := &statistics.DurationSet{}
+	latency.Merge(results.ReadStats)
+	latency.Merge(results.WriteStats)
+	if latency.Count() == 0 {
+		for _, name := range results.OpNames() {
+			latency.Merge(results.OpStats[name])
+		}
+	}
+	return latency
+}
+
+// ErrorRate is the fraction of the operations that failed or returned
+// data that did not verify.
+func (results *Results) ErrorRate() float64 {
+	if results.Ops <= 0 {
+		return 0
+	}
+	return float64(results.ReadErrors+results.WriteErrors+results.VerifyErrors) / float64(results.Ops)
+}
+
+func (results *Results) TPS() float64 {
+	if results.Duration.Seconds() <= 0 {
+		return 0
+	}
+	return float64(results.Ops) / results.Duration.Seconds()
+}
+
+func New(idb db.Interface_DB, SessionName string, config config.Config, arguments arguments.Arguments, data dataset.Dataset) *Engine {
+	var tmp Engine = Engine{}
+	tmp.db = idb
+	tmp.SessionName = SessionName
+	tmp.config = config
+	tmp.arguments = arguments
+	tmp.data = data
+	tmp.written = dataset.NewWritten(arguments.Loops, arguments.Iterations)
+	tmp.reserved = newReservations()
+	return &tmp
+}
+
+// MarkWritten makes a position that already holds data available to the
+// reads of the read/write TPS test.
+func (engine *Engine) MarkWritten(loop int, iter int) {
+	engine.written.Set(loop, iter)
+}
+
+// ReportIntervals makes the TPS and YCSB tests report their results every
+// interval while they run.
+func (engine *Engine) ReportIntervals(interval time.Duration, report IntervalReport) {
+	engine.interval = interval
+	engine.report = report
+}
+
+// roll is called before every operation of a worker. It hands the results
+// of the worker over when the -warmup ends, or when the worker moved to the
+// next -report-interval.
+func (engine *Engine) roll(res *Results) *Results {
+	if engine.warmup != nil {
+		warm := engine.warmup.active()
+		if warm && !res.warmup {
+			res.warmup = true
+		} else if !warm && res.warmup {
+			engine.collect(res)
+			interval := res.interval
+			res = NewResults()
+			res.interval = interval
+		}
+	}
+	if engine.intervals == nil {
+		return res
+	}
+	return engine.intervals.roll(res)
+}
+
+// collect takes over results a worker handed over: to the intervals with a
+// -report-interval, otherwise to the warm-up results.
+func (engine *Engine) collect(res *Results) {
+	if engine.intervals != nil {
+		engine.intervals.add(res)
+	} else {
+		engine.warmup.add(res)
+	}
+}
+
+// DistributionOptions are the -zipf-theta, -hot-fraction and -hot-ops
+// parameters of the request distribution.
+func DistributionOptions(arguments arguments.Arguments) distribution.Options {
+	return distribution.Options{Theta: arguments.ZipfTheta, HotFraction: arguments.HotFraction, HotOps: arguments.HotOps}
+}
+
+// setDistribution selects the -dist distribution the TPS workers pick their
+// records with.
+func (engine *Engine) setDistribution() (err error) {
+	name := engine.arguments.Distribution
+	if len(name) == 0 {
+		name = "uniform"
+	}
+	if engine.chooser, err = distribution.New(name, DistributionOptions(engine.arguments)); err != nil {
+		return err
+	}
+	fmt.Printf("Request distribution: %s\n", name)
+	return nil
+}
+
+func (engine *Engine) GetReadErrors() int {
+	return engine.ReadErrors
+}
+
+func (engine *Engine) GetWriteErrors() int {
+	return engine.WriteErrors
+}
+
+func (engine *Engine) GetVerifyErrors() int {
+	return engine.VerifyErrors
+}
+
+func (engine *Engine) WriteSequentialTestData(ctx context.Context, ch chan Results, delay time.Duration, duration int, loopstart int, loopend int, iterstart int, iterend int) {
+	defer close(ch)
+	startTest := time.Now()
+	start := time.Now().Unix()
+	stop := start + (int64(duration) * 60)
+	res := NewResults()
+
+	for time.Now().Unix() < stop && ctx.Err() == nil {
+		for loop := loopstart; loop < loopend; loop++ {
+			for iter := iterstart; iter < iterend && ctx.Err() == nil; iter++ {
+				res = engine.roll(res)
+				intended := engine.pace(ctx, delay)
+				StartWrite := time.Now()
+				err := engine.db.WriteData(ctx, engine.SessionName, loop, iter, engine.data.Key(loop, iter), engine.data.Data(loop, iter))
+				if ctx.Err() != nil {
+					break
+				}
+				if err != nil {
+					res.WriteErrors++
+				}
+				res.WriteStats.Add(time.Since(StartWrite))
+				engine.recordOpenLoop(res, intended, StartWrite)
+				res.Ops++
+			}
+		}
+	}
+	res.Duration = time.Since(startTest)
+	ch <- *res
+}
+
+// readOrWriteTestData runs the operations of the -mix. Inserts write a new
+// record of the worker's loop, the other operations pick a record that was
+// written, from any loop.
+func (engine *Engine) readOrWriteTestData(ctx context.Context, ch chan Results, random *rand.Rand, delay time.Duration, duration int, loop int, iter int) {
+	defer close(ch)
+	startTest := time.Now()
+	start := time.Now().Unix()
+	stop := start + (int64(duration) * 60)
+	res := NewResults()
+
+	for time.Now().Unix() < stop && ctx.Err() == nil {
+		res = engine.roll(res)
+		var err error
+		var ok bool
+		var rX, rY int
+
+		operation := engine.mix.choose(random) // DETERMINE THE NEXT OPERATION
+		if operation == OpInsert {
+			rX, rY, ok = loop, engine.insertPosition(loop, iter), true
+		} else {
+			rX, rY, ok = engine.written.Find(engine.chooser.Next(random, engine.written.Size()))
+		}
+		if ok && operation != OpScan {
+			ok = engine.reserve(operation, rX, rY)
+		}
+		if !ok { // WAIT AND LOOP AGAIN IF NO DATA WRITTEN OR THE RECORD IS BEING DELETED
+			engine.backoff(ctx)
+			continue
+		}
+
+		// THE RECORDS OF A SCAN ARE RESERVED ONE BY ONE AS THE SCAN REACHES THEM
+		var scanned []int
+		intended := engine.pace(ctx, delay)
+		StartOperation := time.Now()
+		switch operation {
+		case OpRead:
+			err = engine.readRecord(ctx, res, rX, rY)
+		case OpInsert, OpUpdate:
+			// THE RECORDS PAST THE LOADED ONES AREN'T TRACKED, THE OTHER OPERATIONS DON'T PICK THEM
+			if err = engine.writeRecord(ctx, res, rX, rY); err == nil && rY < iter {
+				engine.written.Set(rX, rY)
+			}
+		case OpDelete:
+			engine.written.Clear(rX, rY)
+			err = engine.deleteRecord(ctx, res, rX, rY)
+		case OpReadModifyWrite:
+			if err = engine.readRecord(ctx, res, rX, rY); err == nil {
+				err = engine.writeRecord(ctx, res, rX, rY)
+			}
+		case OpScan:
+			err = engine.scanRecords(ctx, res, rX, rY, 1+random.Intn(DefaultScanLength), func(next int) bool {
+				if next >= iter || !engine.reserve(OpScan, rX, next) {
+					return false
+				}
+				scanned = append(scanned, next)
+				return true
+			})
+		}
+		if operation == OpScan {
+			for _, next := range scanned {
+				engine.reserved.release(rX, next)
+			}
+		} else {
+			engine.reserved.release(rX, rY)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		res.Op(operation).Add(time.Since(StartOperation))
+		engine.recordOpenLoop(res, intended, StartOperation)
+		res.Ops++
+		if err != nil {
+			res.OpErrors[operation]++
+		}
+	}
+	res.Duration = time.Since(startTest)
+	ch <- *res
+}
+
+func (engine *Engine) ReadRandomTestData(ctx context.Context, ch chan Results, random *rand.Rand, delay time.Duration, duration int, loop int, iter int) {
+	defer close(ch)
+	startTest := time.Now()
+	start := time.Now().Unix()
+	stop := start + (int64(duration) * 60)
+	res := NewResults()
+
+	for time.Now().Unix() < stop && ctx.Err() == nil {
+		res = engine.roll(res)
+		readIter := int(engine.chooser.Next(random, int64(iter)))
+		intended := engine.pace(ctx, delay)
+		StartRead := time.Now()
+		data, err := engine.db.ReadData(ctx, engine.SessionName, loop, readIter, engine.data.Key(loop, readIter))
+		if ctx.Err() != nil {
+			break
+		}
+		res.ReadStats.Add(time.Since(StartRead))
+		engine.recordOpenLoop(res, intended, StartRead)
+		res.Ops++
+		if err != nil {
+			res.ReadErrors++
+			continue
+		}
+		StartVerify := time.Now()
+		if err := checkData(engine.data.Data(loop, readIter), data, engine.arguments.NoDataCheck); err != nil {
+			res.VerifyErrors++
+		}
+		res.VerifyStats.Add(time.Since(StartVerify))
+	}
+	res.Duration = time.Since(startTest)
+	ch <- *res
+}
+
+// pace waits for the next operation of a worker. With -rate it waits for the
+// next slot of the schedule and returns its intended start time, otherwise
+// it waits for the -delay and returns the current time.
+func (engine *Engine) pace(ctx context.Context, delay time.Duration) time.Time {
+	if engine.schedule == nil {
+		sleep(ctx, delay)
+		return time.Now()
+	}
+	intended := engine.schedule.Next()
+	sleep(ctx, time.Until(intended))
+	return intended
+}
+
+// recordOpenLoop adds an operation to the corrected and uncorrected
+// latencies of a -rate test.
+func (engine *Engine) recordOpenLoop(res *Results, intended time.Time, started time.Time) {
+	if engine.schedule == nil {
+		return
+	}
+	res.Corrected.Add(time.Since(intended))
+	res.Uncorrected.Add(time.Since(started))
+}
+
+// sleep waits for the delay between operations unless the test is stopped.
+func sleep(ctx context.Context, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func checkData(ctrl string, tst string, nodatacheck bool) (err error) {
+	if nodatacheck {
+		return nil
+	}
+
+	if ctrl == tst {
+		return nil
+	}
+
+	err = fmt.Errorf("!! Data mismatch !!\nExpected: %s\nReceived:%s", ctrl, tst)
+	return err
+}
+
+// runWorkers starts one TPS worker per loop (run one after the other when
+// there is a single worker, otherwise all at once) and merges their results.
+// Every worker gets its own random generator seeded from -seed and the loop,
+// so a run with the same seed replays the same operations.
+func (engine *Engine) runWorkers(ctx context.Context, worker func(ctx context.Context, ch chan Results, random *rand.Rand, loop int, delay time.Duration)) (results Results) {
+	var workers map[int]chan Results
+
+	arguments := engine.arguments
+	results = *NewResults()
+
+	StartTest := time.Now()
+	intervalDuration := timeparse.ParseDuration(arguments.Interval)
+	workers = make(map[int]chan Results)
+
+	engine.schedule = nil
+	if engine.stage != nil && engine.stage.Rate > 0 {
+		engine.schedule = NewRampSchedule(engine.stage.Rate, engine.stage.Target, engine.stage.Hold)
+	} else if arguments.Rate > 0 {
+		engine.schedule = NewSchedule(arguments.Rate)
+		fmt.Printf("Open loop at %v operations/sec...", arguments.Rate)
+	}
+
+	// THE -warmup RESULTS ARE SET APART, WITH -report-interval THE RESULTS ARE THE MERGED INTERVALS
+	engine.warmup = nil
+	if len(arguments.Warmup) > 0 {
+		duration, ops, _ := ParseWarmup(arguments.Warmup)
+		engine.warmup = newWarmup(duration, ops)
+	}
+	if engine.interval > 0 {
+		engine.intervals = newIntervals(engine.interval, engine.report)
+	}
+	collect := func(res *Results) {
+		if engine.intervals != nil || res.warmup {
+			engine.collect(res)
+		} else {
+			results.Merge(res)
+		}
+	}
+
+	fmt.Printf("Starting %d workers...", arguments.TpsWorkers)
+
+	if engine.stage != nil {
+		// THE WORKERS OF A PROFILE STAGE ALWAYS RUN AT ONCE, SHARING THE TABLES
+		for channel := 0; channel < arguments.TpsWorkers; channel++ {
+			workers[channel] = make(chan Results, arguments.TpsWorkers)
+			go worker(ctx, workers[channel], engine.workerRand(channel), channel%arguments.Loops, intervalDuration)
+		}
+
+		for channel := range workers {
+			res := <-workers[channel]
+			collect(&res)
+		}
+	} else if arguments.TpsWorkers == 1 {
+		// SINGLE WORKER
+		for loop := 0; loop < arguments.Loops; loop++ {
+			fmt.Printf("Loop %d...", loop+1)
+			workers[loop] = make(chan Results, 1)
+			go worker(ctx, workers[loop], engine.workerRand(loop), loop, intervalDuration)
+			res := <-workers[loop]
+			collect(&res)
+		}
+	} else {
+		// FOR MULTIPLE WORKERS
+		for channel := 0; channel < arguments.TpsWorkers; channel++ {
+			workers[channel] = make(chan Results, arguments.TpsWorkers)
+			go worker(ctx, workers[channel], engine.workerRand(channel), channel, intervalDuration)
+		}
+
+		fmt.Printf("Started!  Test is running...")
+
+		for channel := range workers {
+			res := <-workers[channel]
+			collect(&res)
+		}
+	}
+
+	if engine.intervals != nil {
+		total, warm := engine.intervals.close()
+		results = *total
+		if engine.warmup != nil {
+			engine.warmup.add(warm)
+		}
+		engine.intervals = nil
+	}
+
+	results.Duration = time.Since(StartTest)
+	if engine.warmup != nil {
+		// THE THROUGHPUT IS MEASURED FROM THE END OF THE WARM-UP
+		results.Warmup = engine.warmup.results
+		results.Warmup.Duration = results.Duration
+		if ended := engine.warmup.end(); !ended.IsZero() {
+			results.Warmup.Duration = ended.Sub(StartTest)
+			results.Duration = time.Since(ended)
+		} else {
+			results.Duration = 0
+		}
+		engine.warmup = nil
+	}
+	return results
+}
+
+func (engine *Engine) workerRand(loop int) *rand.Rand {
+	return rand.New(rand.NewSource(engine.arguments.Seed + int64(loop)*1000003))
+}
+
+func (engine *Engine) TPSTestR(ctx context.Context) (results Results, err error) {
+	arguments := engine.arguments
+
+	if err = engine.setDistribution(); err != nil {
+		return results, err
+	}
+	prepare := engine.PrepareTPSTestR(ctx)
+
+	results = engine.runWorkers(ctx, func(ctx context.Context, ch chan Results, random *rand.Rand, loop int, delay time.Duration) {
+		engine.ReadRandomTestData(ctx, ch, random, delay, arguments.Duration, loop, arguments.Iterations)
+	})
+	results.WriteErrors = results.WriteErrors + prepare.WriteErrors
+	results.WriteStats.Merge(prepare.WriteStats)
+
+	return results, nil
+}
+
+// PrepareTPSTestR writes the sample records the read TPS test reads, unless
+// the session is reused or they were already written (before the first
+// stage of a -profile).
+func (engine *Engine) PrepareTPSTestR(ctx context.Context) (prepare *Results) {
+	arguments := engine.arguments
+	prepare = NewResults()
+
+	if i := len(arguments.Sessovrd); i == 0 && !engine.prepared {
+		engine.prepared = true
+		for loop := 0; loop < arguments.Loops && ctx.Err() == nil; loop++ {
+			fmt.Printf("Loop %d: Preparing database with sample records...", loop+1)
+			for iter := 0; iter < arguments.Iterations && ctx.Err() == nil; iter++ {
+				StartWrite := time.Now()
+				err := engine.db.WriteData(ctx, engine.SessionName, loop, iter, engine.data.Key(loop, iter), engine.data.Data(loop, iter))
+				if ctx.Err() != nil {
+					break
+				}
+				if err != nil {
+					fmt.Printf("\nLoop: %d, Iteration: %d --  %s\n", loop+1, iter+1, err)
+					prepare.WriteErrors++
+				}
+				StopWrite := time.Since(StartWrite)
+				prepare.WriteStats.Add(StopWrite)
+				engine.written.Set(loop, iter)
+			}
+			fmt.Printf("Complete.\n")
+		}
+	}
+
+	return prepare
+}
+
+func (engine *Engine) TPSTestW(ctx context.Context) (results Results, err error) {
+	arguments := engine.arguments
+
+	results = engine.runWorkers(ctx, func(ctx context.Context, ch chan Results, random *rand.Rand, loop int, delay time.Duration) {
+		engine.WriteSequentialTestData(ctx, ch, delay, arguments.Duration, loop, loop+1, 0, arguments.Iterations)
+	})
+
+	return results, nil
+}
+
+// insertPosition reserves the next new record of a loop: the positions of
+// the loop that weren't written yet, then the positions past the loaded
+// records.
+func (engine *Engine) insertPosition(loop int, iter int) int {
+	for {
+		position := int(engine.inserts[loop].reserve())
+		if position >= iter || !engine.written.IsSet(loop, position) {
+			return position
+		}
+	}
+}
+
+// reserve keeps the other workers from deleting a record while an operation
+// uses it, or from using it while it is deleted. The record must still be
+// written once reserved, except for an insert.
+func (engine *Engine) reserve(operation string, loop int, iter int) bool {
+	if operation == OpDelete {
+		if !engine.reserved.lock(loop, iter) {
+			return false
+		}
+	} else if !engine.reserved.share(loop, iter) {
+		return false
+	}
+	if operation != OpInsert && !engine.written.IsSet(loop, iter) {
+		engine.reserved.release(loop, iter)
+		return false
+	}
+	return true
+}
+
+// backoff waits a little before a worker that found no record to operate on
+// tries again, instead of spinning until records are written.
+func (engine *Engine) backoff(ctx context.Context) {
+	timer := time.NewTimer(time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// reservations are the records used by the operations in flight: shared by
+// the reads and writes, held alone by a delete. Nothing waits, an operation
+// that can't reserve its record picks another one.
+type reservations struct {
+	holders map[[2]int]int
+	mutex   sync.Mutex
+}
+
+func newReservations() *reservations {
+	var tmp reservations = reservations{}
+	tmp.holders = make(map[[2]int]int)
+	return &tmp
+}
+
+func (reserved *reservations) share(loop int, iter int) bool {
+	reserved.mutex.Lock()
+	defer reserved.mutex.Unlock()
+	if reserved.holders[[2]int{loop, iter}] < 0 {
+		return false
+	}
+	reserved.holders[[2]int{loop, iter}]++
+	return true
+}
+
+func (reserved *reservations) lock(loop int, iter int) bool {
+	reserved.mutex.Lock()
+	defer reserved.mutex.Unlock()
+	if reserved.holders[[2]int{loop, iter}] != 0 {
+		return false
+	}
+	reserved.holders[[2]int{loop, iter}] = -1
+	return true
+}
+
+func (reserved *reservations) release(loop int, iter int) {
+	reserved.mutex.Lock()
+	defer reserved.mutex.Unlock()
+	if holders := reserved.holders[[2]int{loop, iter}]; holders > 1 {
+		reserved.holders[[2]int{loop, iter}]--
+	} else {
+		delete(reserved.holders, [2]int{loop, iter})
+	}
+}
+
+func (engine *Engine) TPSTestRW(ctx context.Context) (results Results, err error) {
+	arguments := engine.arguments
+
+	// THE STORED PATTERNS HAVE NO NEW RECORDS TO INSERT, THEIR RECORDS ARE UPDATED INSTEAD
+	_, stored := engine.data.(*dataset.Stored)
+	spec := arguments.Mix
+	if len(spec) == 0 && stored {
+		spec = DefaultStoredMix
+	} else if len(spec) == 0 {
+		spec = DefaultMix
+	}
+	if engine.mix, err = ParseMix(spec); err != nil {
+		return results, err
+	}
+	if stored && engine.mix.Has(OpInsert) {
+		return results, fmt.Errorf("-mix insert needs generated data, the -stored patterns have no new records")
+	}
+	engine.inserts = make([]*keyspace, arguments.Loops)
+	for loop := range engine.inserts {
+		engine.inserts[loop] = newKeyspace(0)
+	}
+	if err = engine.setDistribution(); err != nil {
+		return results, err
+	}
+	fmt.Printf("Operation mix: %s\n", engine.mix)
+
+	results = engine.runWorkers(ctx, func(ctx context.Context, ch chan Results, random *rand.Rand, loop int, delay time.Duration) {
+		engine.readOrWriteTestData(ctx, ch, random, delay, arguments.Duration, loop, arguments.Iterations)
+	})
+
+	return results, nil
+}
+
+// Load writes every record of every table of the session once, for the run
+// command to benchmark them later. Each worker loads its own table.
+func (engine *Engine) Load(ctx context.Context) (results Results, err error) {
+	fmt.Printf("Loading %d records in each of the %d tables...", engine.arguments.Iterations, engine.arguments.Loops)
+	results = engine.runWorkers(ctx, func(ctx context.Context, ch chan Results, random *rand.Rand, loop int, delay time.Duration) {
+		engine.loadRecords(ctx, ch, delay, loop)
+	})
+	fmt.Printf("Complete.\n")
+
+	return results, nil
+}
+
+func (engine *Engine) loadRecords(ctx context.Context, ch chan Results, delay time.Duration, loop int) {
+	defer close(ch)
+	startTest := time.Now()
+	res := NewResults()
+
+	for iter := 0; iter < engine.arguments.Iterations && ctx.Err() == nil; iter++ {
+		res = engine.roll(res)
+		intended := engine.pace(ctx, delay)
+		StartWrite := time.Now()
+		err := engine.writeRecord(ctx, res, loop, iter)
+		if ctx.Err() != nil {
+			break
+		}
+		engine.recordOpenLoop(res, intended, StartWrite)
+		res.Ops++
+		if err == nil {
+			engine.written.Set(loop, iter)
+		}
+	}
+	res.Duration = time.Since(startTest)
+	ch <- *res
+}
+
+func (engine *Engine) TestCycle(ctx context.Context, currentLoop int, wg *sync.WaitGroup, read_stats *statistics.DurationSet, write_stats *statistics.DurationSet, verify_stats *statistics.DurationSet) (err error) {
+	defer wg.Done()
+
+	arguments := engine.arguments
+
+	// THE READ TEST OF THE run COMMAND ONLY READS THE LOADED RECORDS
+	if !(arguments.Command == "run" && arguments.Mode == "r") {
+		fmt.Printf("Loop %d: Beginning Write Test...\n", currentLoop+1)
+		StartLoop := time.Now()
+		for iter := 0; iter < arguments.Iterations && ctx.Err() == nil; iter++ {
+			StartWrite := time.Now()
+			err := engine.db.WriteData(ctx, engine.SessionName, currentLoop, iter, engine.data.Key(currentLoop, iter), engine.data.Data(currentLoop, iter))
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				fmt.Printf("Loop: %d, Iteration: %d --  %s\n", currentLoop+1, iter+1, err)
+				engine.countError(&engine.WriteErrors)
+			}
+			StopWrite := time.Since(StartWrite)
+			write_stats.Add(StopWrite)
+		}
+		StopLoop := time.Since(StartLoop)
+
+		fmt.Printf("Loop %d: Write Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
+	}
+
+	if arguments.Mode == "w" || ctx.Err() != nil {
+		return
+	}
+
+	// READ DATA AND VERIFY
+	fmt.Printf("Loop %d: Beginning Read and Verify...\n", currentLoop+1)
+	StartLoop := time.Now()
+	for iter := 0; iter < arguments.Iterations && ctx.Err() == nil; iter++ {
+		StartRead := time.Now()
+		data, err := engine.db.ReadData(ctx, engine.SessionName, currentLoop, iter, engine.data.Key(currentLoop, iter))
+		if ctx.Err() != nil {
+			break
+		}
+		StopRead := time.Since(StartRead)
+		read_stats.Add(StopRead)
+		if err != nil {
+			fmt.Printf("Loop %d, Iteration: %d -- %s\n", currentLoop+1, iter+1, err)
+			engine.countError(&engine.ReadErrors)
+			continue
+		}
+
+		StartVerify := time.Now()
+		err = checkData(engine.data.Data(currentLoop, iter), data, arguments.NoDataCheck)
+		verify_stats.Add(time.Since(StartVerify))
+		if err != nil {
+			fmt.Printf("Loop %d, Iteration: %d\n%s", currentLoop+1, iter+1, err)
+			engine.countError(&engine.VerifyErrors)
+		}
+	}
+	StopLoop := time.Since(StartLoop)
+	fmt.Printf("Loop %d: Read Test Completed. (%s elapsed)\n", currentLoop+1, StopLoop)
+
+	return nil
+}
+
+func (engine *Engine) countError(counter *int) {
+	engine.mux.Lock()
+	*counter++
+	engine.mux.Unlock()
+}
+
+// readRecord reads and verifies a record. Only a failed read is returned as
+// an error, a mismatch is counted as a verify error.
+func (engine *Engine) readRecord(ctx context.Context, res *Results, loop int, iter int) (err error) {
+	StartRead := time.Now()
+	data, err := engine.db.ReadData(ctx, engine.SessionName, loop, iter, engine.data.Key(loop, iter))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	res.ReadStats.Add(time.Since(StartRead))
+	if err != nil {
+		res.ReadErrors++
+		return err
+	}
+
+	StartVerify := time.Now()
+	if err := checkData(engine.data.Data(loop, iter), data, engine.arguments.NoDataCheck); err != nil {
+		res.VerifyErrors++
+	}
+	res.VerifyStats.Add(time.Since(StartVerify))
+	return nil
+}
+
+// writeRecord writes the generated value of a record. Updates rewrite the
+// same value so that later reads can still be verified.
+func (engine *Engine) writeRecord(ctx context.Context, res *Results, loop int, iter int) (err error) {
+	StartWrite := time.Now()
+	err = engine.db.WriteData(ctx, engine.SessionName, loop, iter, engine.data.Key(loop, iter), engine.data.Data(loop, iter))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	res.WriteStats.Add(time.Since(StartWrite))
+	if err != nil {
+		res.WriteErrors++
+	}
+	return err
+}
+
+func (engine *Engine) deleteRecord(ctx context.Context, res *Results, loop int, iter int) (err error) {
+	err = engine.db.DeleteData(ctx, engine.SessionName, loop, iter, engine.data.Key(loop, iter))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		res.WriteErrors++
+	}
+	return err
+}
+
+// scanRecords reads up to length records from a start record. Drivers
+// without a range read (db.Scanner) read the following readable records
+// one by one.
+func (engine *Engine) scanRecords(ctx context.Context, res *Results, loop int, start int, length int, readable func(iter int) bool) (err error) {
+	if scanner, ok := engine.db.(db.Scanner); ok {
+		if _, err = scanner.ScanData(ctx, engine.SessionName, loop, engine.data.Key(loop, start), length); err != nil && ctx.Err() == nil {
+			res.ReadErrors++
+		}
+		return err
+	}
+
+	for iter := start; iter < start+length && readable(iter); iter++ {
+		if err = engine.readRecord(ctx, res, loop, iter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
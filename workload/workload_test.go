@@ -0,0 +1,282 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hartsp2000/benchmark_db/arguments"
+	"github.com/hartsp2000/benchmark_db/config"
+	"github.com/hartsp2000/benchmark_db/dataset"
+	"github.com/hartsp2000/benchmark_db/distribution"
+)
+
+var errNotFound = errors.New("key not found")
+
+// memoryDB is an in-memory db.Interface_DB. Every operation takes a little
+// time so that the workers overlap as they would on a real database.
+type memoryDB struct {
+	records map[string]string
+	calls   int
+	mutex   sync.Mutex
+}
+
+func newMemoryDB() *memoryDB {
+	var tmp memoryDB = memoryDB{}
+	tmp.records = make(map[string]string)
+	return &tmp
+}
+
+func (db *memoryDB) operation() {
+	db.mutex.Lock()
+	db.calls++
+	db.mutex.Unlock()
+	// time.Sleep WAITS A MILLISECOND OR MORE ON SOME SYSTEMS
+	for start := time.Now(); time.Since(start) < 10*time.Microsecond; {
+	}
+}
+
+func (db *memoryDB) Connect(ctx context.Context, config config.Config, arguments arguments.Arguments) (err error) {
+	return nil
+}
+
+func (db *memoryDB) CreateTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	return nil
+}
+
+func (db *memoryDB) ReadPatternData(ctx context.Context, SessionName string, config config.Config, arguments arguments.Arguments) (JunkKey [][]string, JunkData [][]string, err error) {
+	return nil, nil, nil
+}
+
+func (db *memoryDB) WriteData(ctx context.Context, SessionName string, loop int, iter int, key string, data string) (err error) {
+	db.operation()
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.records[key] = data
+	return nil
+}
+
+func (db *memoryDB) ReadData(ctx context.Context, SessionName string, loop int, iter int, key string) (data string, err error) {
+	db.operation()
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	data, ok := db.records[key]
+	if !ok {
+		return "", errNotFound
+	}
+	return data, nil
+}
+
+func (db *memoryDB) DeleteData(ctx context.Context, SessionName string, loop int, iter int, key string) (err error) {
+	db.operation()
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	delete(db.records, key)
+	return nil
+}
+
+func (db *memoryDB) DropTestTables(ctx context.Context, SessionName string, nb_tables int) (err error) {
+	return nil
+}
+
+func (db *memoryDB) SaveSession(ctx context.Context, SessionName string, info string) (err error) {
+	return nil
+}
+
+func (db *memoryDB) LoadSessions(ctx context.Context) (sessions map[string]string, err error) {
+	return nil, nil
+}
+
+func (db *memoryDB) DeleteSession(ctx context.Context, SessionName string) (err error) {
+	return nil
+}
+
+func (db *memoryDB) Close() (err error) {
+	return nil
+}
+
+// newTestEngine returns an engine running the -mix on loops tables of
+// iterations records, one worker per table, the first loaded records
+// already written.
+func newTestEngine(idb *memoryDB, mix string, loops int, iterations int, loaded int) *Engine {
+	args := arguments.Arguments{}
+	args.Loops = loops
+	args.Iterations = iterations
+	args.KeyBS = 20
+	args.DataBS = 64
+	args.TpsWorkers = loops
+	args.Duration = 1
+	args.Mix = mix
+	args.Seed = 1
+	args.ZipfTheta = distribution.DefaultOptions.Theta
+	args.HotFraction = distribution.DefaultOptions.HotFraction
+	args.HotOps = distribution.DefaultOptions.HotOps
+	args.MaxErrorRate = 0
+
+	engine := New(idb, "TEST", config.Config{}, args, dataset.NewGenerator(1, args.KeyBS, args.DataBS))
+	for loop := 0; loop < loops; loop++ {
+		for iter := 0; iter < loaded; iter++ {
+			idb.records[engine.data.Key(loop, iter)] = engine.data.Data(loop, iter)
+			engine.MarkWritten(loop, iter)
+		}
+	}
+	return engine
+}
+
+func runTestEngine(t *testing.T, engine *Engine, duration time.Duration) Results {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	results, err := engine.TPSTestRW(ctx)
+	if err != nil {
+		t.Fatalf("TPSTestRW: %s", err)
+	}
+	return results
+}
+
+func TestMixOperationCounts(t *testing.T) {
+	tests := []struct {
+		mix    string
+		loaded int
+		shares map[string]float64
+	}{
+		{"read=0.5,insert=0.5", 0, map[string]float64{OpRead: 0.5, OpInsert: 0.5}},
+		{"read=0.6,update=0.3,rmw=0.1", 200, map[string]float64{OpRead: 0.6, OpUpdate: 0.3, OpReadModifyWrite: 0.1}},
+		{"read=0.4,insert=0.3,scan=0.2,update=0.1", 200, map[string]float64{OpRead: 0.4, OpInsert: 0.3, OpScan: 0.2,
+			OpUpdate: 0.1}},
+	}
+
+	for _, test := range tests {
+		engine := newTestEngine(newMemoryDB(), test.mix, 2, 200, test.loaded)
+		results := runTestEngine(t, engine, 300*time.Millisecond)
+
+		counted := int64(0)
+		for _, known := range mixOperations {
+			if stats, ok := results.OpStats[known.operation]; ok {
+				counted += int64(stats.Count())
+			}
+		}
+		if results.Ops < 1000 || counted != results.Ops {
+			t.Fatalf("%s: %d operations counted per type, %d in total", test.mix, counted, results.Ops)
+		}
+		for _, known := range mixOperations {
+			share := 0.0
+			if stats, ok := results.OpStats[known.operation]; ok {
+				share = float64(stats.Count()) / float64(results.Ops)
+			}
+			if math.Abs(share-test.shares[known.operation]) > 0.05 {
+				t.Errorf("%s: %s ran %.3f of the operations, want %.3f", test.mix, known.operation, share,
+					test.shares[known.operation])
+			}
+			if results.OpErrors[known.operation] > 0 {
+				t.Errorf("%s: %d %s errors", test.mix, results.OpErrors[known.operation], known.operation)
+			}
+		}
+		if errors := results.ReadErrors + results.WriteErrors + results.VerifyErrors; errors > 0 {
+			t.Errorf("%s: %d errors", test.mix, errors)
+		}
+	}
+}
+
+// TestMixDeletes checks that the records deleted by a worker aren't counted
+// as errors of the reads, scans and read-modify-writes of the others.
+func TestMixDeletes(t *testing.T) {
+	mixes := []string{
+		"read=0.3,insert=0.3,delete=0.3,rmw=0.1",
+		"read=1,update=1,delete=1,scan=1,insert=1",
+		"delete=0.5,rmw=0.5",
+	}
+
+	for _, mix := range mixes {
+		// FEW RECORDS FOR THE WORKERS TO OFTEN PICK THE SAME ONES
+		engine := newTestEngine(newMemoryDB(), mix, 4, 20, 20)
+		results := runTestEngine(t, engine, 300*time.Millisecond)
+
+		if results.OpStats[OpDelete] == nil || results.OpStats[OpDelete].Count() == 0 {
+			t.Errorf("%s: no deletes ran", mix)
+		}
+		for operation, errors := range results.OpErrors {
+			if errors > 0 {
+				t.Errorf("%s: %d %s errors", mix, errors, operation)
+			}
+		}
+		if errors := results.ReadErrors + results.WriteErrors + results.VerifyErrors; errors > 0 {
+			t.Errorf("%s: %d errors", mix, errors)
+		}
+	}
+}
+
+func TestInsertPosition(t *testing.T) {
+	engine := newTestEngine(newMemoryDB(), "insert=1", 2, 6, 0)
+	engine.inserts = []*keyspace{newKeyspace(0), newKeyspace(0)}
+	engine.MarkWritten(0, 0)
+	engine.MarkWritten(0, 2)
+	engine.MarkWritten(0, 3)
+
+	// THE POSITIONS THAT WEREN'T WRITTEN, THEN THE ONES PAST THE LOADED RECORDS
+	want := []int{1, 4, 5, 6, 7}
+	for _, position := range want {
+		if got := engine.insertPosition(0, 6); got != position {
+			t.Fatalf("insertPosition(0) = %d, want %d", got, position)
+		}
+	}
+	if got := engine.insertPosition(1, 6); got != 0 {
+		t.Errorf("insertPosition(1) = %d, want 0", got)
+	}
+}
+
+func TestInsertPositionConcurrent(t *testing.T) {
+	const workers = 8
+	const inserts = 500
+
+	engine := newTestEngine(newMemoryDB(), "insert=1", 1, 1000, 0)
+	engine.inserts = []*keyspace{newKeyspace(0)}
+	for iter := 0; iter < 1000; iter += 2 {
+		engine.MarkWritten(0, iter)
+	}
+
+	positions := make(chan int, workers*inserts)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < inserts; j++ {
+				positions <- engine.insertPosition(0, 1000)
+			}
+		}()
+	}
+	wg.Wait()
+	close(positions)
+
+	seen := make(map[int]bool)
+	for position := range positions {
+		if seen[position] {
+			t.Fatalf("position %d was reserved twice", position)
+		}
+		if position < 1000 && position%2 == 0 {
+			t.Fatalf("position %d was already written", position)
+		}
+		seen[position] = true
+	}
+}
+
+// TestNoWrittenRecords checks that the operations that need a written record
+// wait for one instead of failing or reading missing records.
+func TestNoWrittenRecords(t *testing.T) {
+	idb := newMemoryDB()
+	engine := newTestEngine(idb, "read=1,update=1,delete=1,scan=1,rmw=1", 2, 100, 0)
+	results := runTestEngine(t, engine, 100*time.Millisecond)
+
+	if results.Ops != 0 {
+		t.Errorf("%d operations ran without a written record", results.Ops)
+	}
+	if idb.calls != 0 {
+		t.Errorf("%d database calls without a written record", idb.calls)
+	}
+	if errors := results.ReadErrors + results.WriteErrors + results.VerifyErrors; errors > 0 {
+		t.Errorf("%d errors", errors)
+	}
+}
@@ -0,0 +1,307 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"github.com/hartsp2000/benchmark_db/distribution"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// YCSB OPERATION NAMES, AS YCSB REPORTS THEM
+const (
+	OpRead            = "READ"
+	OpUpdate          = "UPDATE"
+	OpInsert          = "INSERT"
+	OpScan            = "SCAN"
+	OpReadModifyWrite = "READ-MODIFY-WRITE"
+	OpDelete          = "DELETE"
+	OpLoad            = "LOAD"
+)
+
+// CoreWorkloads are the properties of the workloada to workloadf files that
+// ship with YCSB. Proportions that aren't listed are 0.
+var CoreWorkloads = map[string]map[string]string{
+	"a": {"readproportion": "0.5", "updateproportion": "0.5", "requestdistribution": "zipfian"},
+	"b": {"readproportion": "0.95", "updateproportion": "0.05", "requestdistribution": "zipfian"},
+	"c": {"readproportion": "1", "requestdistribution": "zipfian"},
+	"d": {"readproportion": "0.95", "insertproportion": "0.05", "requestdistribution": "latest"},
+	"e": {"scanproportion": "0.95", "insertproportion": "0.05", "requestdistribution": "zipfian", "maxscanlength": "100"},
+	"f": {"readproportion": "0.5", "readmodifywriteproportion": "0.5", "requestdistribution": "zipfian"},
+}
+
+// CoreWorkload is a YCSB core workload: the record and operation counts,
+// the proportions of the operations and the request distribution. All the
+// records live in the first test table of the session.
+type CoreWorkload struct {
+	Name                string
+	RecordCount         int64
+	OperationCount      int64
+	RequestDistribution string
+	MaxScanLength       int
+	Options             distribution.Options
+	mix                 *Mix
+}
+
+// NewCoreWorkload builds a core workload (a to f, or workloada to workloadf)
+// with its properties overridden by the YCSB style properties given.
+// recordCount and options are used when the recordcount, zipfianconstant,
+// hotspotdatafraction and hotspotopnfraction properties aren't set.
+func NewCoreWorkload(name string, properties map[string]string, recordCount int64, options distribution.Options) (workload *CoreWorkload, err error) {
+	defaults, ok := CoreWorkloads[strings.TrimPrefix(strings.ToLower(name), "workload")]
+	if !ok {
+		return nil, fmt.Errorf("Unknown workload: %s (a to f)", name)
+	}
+
+	var tmp CoreWorkload = CoreWorkload{}
+	tmp.Name = name
+	tmp.RecordCount = recordCount
+	tmp.OperationCount = 1000
+	tmp.RequestDistribution = "uniform"
+	tmp.MaxScanLength = 1000
+	tmp.Options = options
+
+	merged := make(map[string]string)
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range properties {
+		merged[key] = value
+	}
+
+	values := make(map[string]float64)
+	for key, value := range merged {
+		switch key {
+		case "recordcount":
+			tmp.RecordCount, err = strconv.ParseInt(value, 10, 64)
+		case "operationcount":
+			tmp.OperationCount, err = strconv.ParseInt(value, 10, 64)
+		case "maxscanlength":
+			tmp.MaxScanLength, err = strconv.Atoi(value)
+		case "requestdistribution":
+			tmp.RequestDistribution = value
+		case "zipfianconstant":
+			tmp.Options.Theta, err = strconv.ParseFloat(value, 64)
+		case "hotspotdatafraction":
+			tmp.Options.HotFraction, err = strconv.ParseFloat(value, 64)
+		case "hotspotopnfraction":
+			tmp.Options.HotOps, err = strconv.ParseFloat(value, 64)
+		case "readproportion", "updateproportion", "insertproportion", "scanproportion", "readmodifywriteproportion":
+			values[key], err = strconv.ParseFloat(value, 64)
+		default:
+			return nil, fmt.Errorf("Unknown workload property: %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value for workload property %s: %s", key, err)
+		}
+	}
+
+	if _, err = tmp.chooser(); err != nil {
+		return nil, err
+	}
+
+	tmp.mix, err = newMix(map[string]float64{OpRead: values["readproportion"], OpUpdate: values["updateproportion"],
+		OpInsert: values["insertproportion"], OpScan: values["scanproportion"],
+		OpReadModifyWrite: values["readmodifywriteproportion"]})
+	if err != nil {
+		return nil, fmt.Errorf("Workload %s: %s", name, err)
+	}
+	if tmp.RecordCount < 1 || tmp.OperationCount < 0 || tmp.MaxScanLength < 1 {
+		return nil, fmt.Errorf("recordcount and maxscanlength must be at least 1, operationcount can't be negative")
+	}
+
+	return &tmp, nil
+}
+
+// chooser returns the generator of the request distribution. As in YCSB,
+// zipfian means the scrambled zipfian distribution.
+func (workload *CoreWorkload) chooser() (generator distribution.Generator, err error) {
+	if workload.RequestDistribution == "zipfian" {
+		return distribution.New("scrambledzipfian", workload.Options)
+	}
+	return distribution.New(workload.RequestDistribution, workload.Options)
+}
+
+func (workload *CoreWorkload) String() string {
+	return fmt.Sprintf("workload %s: recordcount=%d, operationcount=%d, requestdistribution=%s, %s", workload.Name,
+		workload.RecordCount, workload.OperationCount, workload.RequestDistribution, workload.mix)
+}
+
+// keyspace hands out the positions of the inserted records. A position is
+// only readable once it and every position below it were written, which is
+// how YCSB acknowledges concurrent inserts. The position of a failed insert
+// is handed out again to the next insert, so that the readable positions
+// never cover a record that wasn't written.
+type keyspace struct {
+	next         int64
+	acknowledged int64
+	pending      map[int64]bool
+	failed       []int64
+	mutex        sync.Mutex
+}
+
+func newKeyspace(records int64) *keyspace {
+	var tmp keyspace = keyspace{}
+	tmp.next = records
+	tmp.acknowledged = records
+	tmp.pending = make(map[int64]bool)
+	return &tmp
+}
+
+func (keys *keyspace) reserve() int64 {
+	keys.mutex.Lock()
+	defer keys.mutex.Unlock()
+	if len(keys.failed) > 0 {
+		position := keys.failed[len(keys.failed)-1]
+		keys.failed = keys.failed[:len(keys.failed)-1]
+		return position
+	}
+	position := keys.next
+	keys.next++
+	return position
+}
+
+// acknowledge ends the insert of a reserved position, written tells whether
+// the record made it to the database.
+func (keys *keyspace) acknowledge(position int64, written bool) {
+	keys.mutex.Lock()
+	defer keys.mutex.Unlock()
+	if !written {
+		keys.failed = append(keys.failed, position)
+		return
+	}
+	keys.pending[position] = true
+	for keys.pending[keys.acknowledged] {
+		delete(keys.pending, keys.acknowledged)
+		keys.acknowledged++
+	}
+}
+
+func (keys *keyspace) limit() int64 {
+	keys.mutex.Lock()
+	defer keys.mutex.Unlock()
+	return keys.acknowledged
+}
+
+// YCSBLoad is the load phase: the workers insert recordcount records, each
+// worker a contiguous part of them.
+func (engine *Engine) YCSBLoad(ctx context.Context, workload *CoreWorkload) (results Results, err error) {
+	threads := int64(engine.arguments.TpsWorkers)
+
+	fmt.Printf("Load phase: inserting %d records...", workload.RecordCount)
+	results = engine.runWorkers(ctx, func(ctx context.Context, ch chan Results, random *rand.Rand, thread int, delay time.Duration) {
+		engine.ycsbLoadRecords(ctx, ch, delay, workload.RecordCount*int64(thread)/threads, workload.RecordCount*int64(thread+1)/threads)
+	})
+	fmt.Printf("Complete.\n")
+
+	return results, nil
+}
+
+func (engine *Engine) ycsbLoadRecords(ctx context.Context, ch chan Results, delay time.Duration, start int64, end int64) {
+	defer close(ch)
+	startTest := time.Now()
+	res := NewResults()
+
+	for position := start; position < end && ctx.Err() == nil; position++ {
+		res = engine.roll(res)
+		intended := engine.pace(ctx, delay)
+		StartWrite := time.Now()
+		err := engine.writeRecord(ctx, res, 0, int(position))
+		if ctx.Err() != nil {
+			break
+		}
+		res.Op(OpInsert).Add(time.Since(StartWrite))
+		engine.recordOpenLoop(res, intended, StartWrite)
+		res.Ops++
+		if err != nil {
+			res.OpErrors[OpInsert]++
+		}
+	}
+	res.Duration = time.Since(startTest)
+	ch <- *res
+}
+
+// YCSBRun is the run phase: the workers share operationcount operations
+// (or run until -dur minutes when operationcount is 0 or -dur comes first).
+// The stages of a -profile run until the end of their hold.
+func (engine *Engine) YCSBRun(ctx context.Context, workload *CoreWorkload) (results Results, err error) {
+	chooser, err := workload.chooser()
+	if err != nil {
+		return results, err
+	}
+	// THE INSERTED RECORDS STAY READABLE IN THE NEXT STAGES OF A -profile
+	if engine.keys == nil {
+		engine.keys = newKeyspace(workload.RecordCount)
+	}
+	threads := int64(engine.arguments.TpsWorkers)
+
+	fmt.Printf("Run phase: %s\n", workload)
+	results = engine.runWorkers(ctx, func(ctx context.Context, ch chan Results, random *rand.Rand, thread int, delay time.Duration) {
+		operations := workload.OperationCount / threads
+		if int64(thread) < workload.OperationCount%threads {
+			operations++
+		}
+		if workload.OperationCount == 0 || engine.stage != nil {
+			operations = -1
+		}
+		engine.ycsbRunOperations(ctx, ch, random, delay, workload, chooser, engine.keys, operations)
+	})
+
+	return results, nil
+}
+
+func (engine *Engine) ycsbRunOperations(ctx context.Context, ch chan Results, random *rand.Rand, delay time.Duration, workload *CoreWorkload, chooser distribution.Generator, keys *keyspace, operations int64) {
+	defer close(ch)
+	startTest := time.Now()
+	res := NewResults()
+
+	var stop time.Time
+	if engine.arguments.Duration > 0 {
+		stop = startTest.Add(time.Duration(engine.arguments.Duration) * time.Minute)
+	}
+
+	for done := int64(0); (operations < 0 || done < operations) && ctx.Err() == nil; done++ {
+		if !stop.IsZero() && time.Now().After(stop) {
+			break
+		}
+		res = engine.roll(res)
+		intended := engine.pace(ctx, delay)
+
+		var err error
+		operation := workload.mix.choose(random)
+		StartOperation := time.Now()
+		switch operation {
+		case OpRead:
+			err = engine.readRecord(ctx, res, 0, int(chooser.Next(random, keys.limit())))
+		case OpUpdate:
+			err = engine.writeRecord(ctx, res, 0, int(chooser.Next(random, keys.limit())))
+		case OpInsert:
+			position := keys.reserve()
+			err = engine.writeRecord(ctx, res, 0, int(position))
+			keys.acknowledge(position, err == nil)
+		case OpScan:
+			limit := keys.limit()
+			err = engine.scanRecords(ctx, res, 0, int(chooser.Next(random, limit)), 1+random.Intn(workload.MaxScanLength),
+				func(iter int) bool { return int64(iter) < limit })
+		case OpReadModifyWrite:
+			position := int(chooser.Next(random, keys.limit()))
+			if err = engine.readRecord(ctx, res, 0, position); err == nil {
+				err = engine.writeRecord(ctx, res, 0, position)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		res.Op(operation).Add(time.Since(StartOperation))
+		engine.recordOpenLoop(res, intended, StartOperation)
+		res.Ops++
+		if err != nil {
+			res.OpErrors[operation]++
+		}
+	}
+	res.Duration = time.Since(startTest)
+	ch <- *res
+}
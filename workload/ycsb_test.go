@@ -0,0 +1,82 @@
+package workload
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyspaceAcknowledge(t *testing.T) {
+	keys := newKeyspace(10)
+
+	first, second, third := keys.reserve(), keys.reserve(), keys.reserve()
+	if first != 10 || second != 11 || third != 12 {
+		t.Fatalf("reserved %d, %d, %d, want 10, 11, 12", first, second, third)
+	}
+
+	// A POSITION IS READABLE ONCE EVERY POSITION BELOW IT IS WRITTEN
+	keys.acknowledge(second, true)
+	if limit := keys.limit(); limit != 10 {
+		t.Errorf("limit = %d after acknowledging 11 alone, want 10", limit)
+	}
+	keys.acknowledge(first, false)
+	if limit := keys.limit(); limit != 10 {
+		t.Errorf("limit = %d after the insert of 10 failed, want 10", limit)
+	}
+
+	// THE FAILED POSITION IS HANDED OUT AGAIN
+	if retry := keys.reserve(); retry != first {
+		t.Fatalf("reserved %d after the insert of %d failed, want it again", retry, first)
+	}
+	keys.acknowledge(first, true)
+	if limit := keys.limit(); limit != 12 {
+		t.Errorf("limit = %d, want 12", limit)
+	}
+	keys.acknowledge(third, true)
+	if limit := keys.limit(); limit != 13 {
+		t.Errorf("limit = %d, want 13", limit)
+	}
+}
+
+func TestKeyspaceConcurrentInserts(t *testing.T) {
+	const workers = 8
+	const inserts = 1000
+
+	keys := newKeyspace(0)
+	positions := make([][]int64, workers)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < inserts; j++ {
+				position := keys.reserve()
+				// SOME OF THE INSERTS FAIL, THEIR POSITIONS GO TO THE NEXT INSERTS
+				if position%3 == 0 && j%2 == 0 {
+					keys.acknowledge(position, false)
+					continue
+				}
+				positions[worker] = append(positions[worker], position)
+				keys.acknowledge(position, true)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for worker := range positions {
+		for _, position := range positions[worker] {
+			if seen[position] {
+				t.Fatalf("position %d was written twice", position)
+			}
+			seen[position] = true
+		}
+	}
+	for position := int64(0); position < keys.limit(); position++ {
+		if !seen[position] {
+			t.Fatalf("position %d is below the limit %d but wasn't written", position, keys.limit())
+		}
+	}
+	if keys.limit() < int64(len(seen)-len(keys.failed)) {
+		t.Errorf("limit = %d, want at least %d", keys.limit(), len(seen)-len(keys.failed))
+	}
+}